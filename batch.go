@@ -0,0 +1,134 @@
+package gorbac
+
+// CheckMany tests every permission of `perms` against `roles` under a single lock.
+// A permission is granted if any of the roles has it with the condition `assert`,
+// as AnyGranted does. The result is in the order of `perms`.
+func (rbac *RBAC) CheckMany(roles []string, perms []Permission, assert AssertionFunc) (result []bool) {
+	result = make([]bool, len(perms))
+
+	rbac.mutex.RLock()
+	for _, p := range perms {
+		rbac.observe(p)
+	}
+	if t := rbac.timer(); t.m != nil {
+		defer func() { t.doneMany("CheckMany", perms, result) }()
+	}
+	if logger := rbac.logger; logger != nil {
+		decisions := make([]Decision, len(perms))
+		for i, p := range perms {
+			d := newDecision("CheckMany", roles, p)
+			for _, role := range roles {
+				if rbac.decide(role, p, assert, &d) {
+					result[i] = true
+					break
+				}
+			}
+			d.finish(result[i])
+			decisions[i] = d
+		}
+		rbac.mutex.RUnlock()
+		for _, d := range decisions {
+			logger.LogDecision(d)
+		}
+		return result
+	}
+
+	if rbac.filtered() {
+		for i, p := range perms {
+			for _, id := range roles {
+				if assert != nil && !assert(rbac, id, p) {
+					continue
+				}
+				if _, ok := rbac.recursionCheck(id, p); ok {
+					result[i] = true
+					break
+				}
+			}
+		}
+		rbac.mutex.RUnlock()
+		return result
+	}
+
+	if assert == nil {
+		closure := rbac.closure(roles...)
+		for i, p := range perms {
+			result[i] = permitAny(closure, p)
+		}
+		rbac.mutex.RUnlock()
+		return result
+	}
+
+	closures := make([][]Role, len(roles))
+	for i, id := range roles {
+		closures[i] = rbac.closure(id)
+	}
+	for i, p := range perms {
+		for j, id := range roles {
+			if assert(rbac, id, p) && permitAny(closures[j], p) {
+				result[i] = true
+				break
+			}
+		}
+	}
+	rbac.mutex.RUnlock()
+	return result
+}
+
+// CheckManyMap works like CheckMany, but returns results keyed by permission id.
+func (rbac *RBAC) CheckManyMap(roles []string, perms []Permission, assert AssertionFunc) map[string]bool {
+	list := rbac.CheckMany(roles, perms, assert)
+	result := make(map[string]bool, len(perms))
+	for i, p := range perms {
+		if p != nil {
+			result[p.ID()] = list[i]
+		}
+	}
+	return result
+}
+
+// closure returns the roles `ids` with all ancestors they inherit from, each role once.
+// Edge filters are not taken into account, see resolve.
+// The lock has to be held by the caller.
+func (rbac *RBAC) closure(ids ...string) []Role {
+	var result []Role
+	visited := make(map[string]struct{})
+	for _, id := range ids {
+		inherited, _ := rbac.inherited(id)
+		for _, rid := range inherited {
+			if _, ok := visited[rid]; ok {
+				continue
+			}
+			visited[rid] = empty
+			result = append(result, rbac.roles[rid])
+		}
+	}
+	return result
+}
+
+// resolve returns permissions of the roles `ids` including inherited ones
+// narrowed by edge filters.
+// The lock has to be held by the caller.
+func (rbac *RBAC) resolve(ids ...string) []Permission {
+	var result []Permission
+	if !rbac.filtered() {
+		for _, role := range rbac.closure(ids...) {
+			result = append(result, role.Permissions()...)
+		}
+		return result
+	}
+	for _, id := range ids {
+		for _, o := range rbac.visible(id) {
+			result = append(result, o.p)
+		}
+	}
+	return result
+}
+
+func permitAny(roles []Role, p Permission) bool {
+	for _, role := range roles {
+		if role.Permit(p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,78 @@
+package gorbac
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestRBAC_CheckMany(t *testing.T) {
+	rbac := prepareTree(t)
+	perms := []Permission{
+		NewDeepPermission("task:read"),
+		NewDeepPermission("task:delete"),
+		NewDeepPermission("user:read"),
+		NewDeepPermission("system"),
+		nil,
+	}
+
+	result := rbac.CheckMany([]string{"observer"}, perms, nil)
+	expected := []bool{true, true, true, true, false}
+	for i := range expected {
+		if result[i] != expected[i] {
+			t.Fatalf("%v expected, but %v got", expected, result)
+		}
+	}
+	for i, p := range perms {
+		if p != nil && rbac.IsGranted("observer", p, nil) != result[i] {
+			t.Fatalf("CheckMany differs from IsGranted on %s", p.ID())
+		}
+	}
+
+	result = rbac.CheckMany([]string{"reporter", "not-exist"}, perms[:2], func(_ *RBAC, id string, p Permission) bool {
+		return p.ID() != "task:delete"
+	})
+	if !result[0] || result[1] {
+		t.Fatalf("Unexpected result with assertion: %v", result)
+	}
+
+	m := rbac.CheckManyMap([]string{"reporter"}, []Permission{NewDeepPermission("task:create"), NewDeepPermission("task:read")}, nil)
+	if len(m) != 2 || !m["task:create"] || !m["task:read"] {
+		t.Fatalf("Unexpected result: %v", m)
+	}
+
+	var decisions []Decision
+	rbac.SetDecisionLogger(DecisionLoggerFunc(func(d Decision) { decisions = append(decisions, d) }))
+	result = rbac.CheckMany([]string{"observer"}, perms, nil)
+	if len(decisions) != len(perms) || decisions[0].Op != "CheckMany" || decisions[0].Role != "observer" || !result[3] {
+		t.Fatalf("Unexpected decisions: %+v", decisions)
+	}
+}
+
+func batchPermissions() []Permission {
+	perms := make([]Permission, 50)
+	for i := range perms {
+		perms[i] = NewDeepPermission("resource-" + strconv.Itoa(i) + ":read")
+	}
+	return perms
+}
+
+func BenchmarkRBAC_CheckMany(b *testing.B) {
+	rbac := largeRBAC(b, 1000)
+	perms := batchPermissions()
+	roles := []string{"role-999"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		rbac.CheckMany(roles, perms, nil)
+	}
+}
+
+func BenchmarkRBAC_IsGrantedLoop(b *testing.B) {
+	rbac := largeRBAC(b, 1000)
+	perms := batchPermissions()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, p := range perms {
+			rbac.IsGranted("role-999", p, nil)
+		}
+	}
+}
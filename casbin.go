@@ -0,0 +1,178 @@
+package gorbac
+
+import (
+	"encoding/csv"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CasbinIssue describes a Casbin policy line which can't be represented.
+type CasbinIssue struct {
+	// Line is the number of the line on import, zero on export.
+	Line int
+	// Text is the policy line.
+	Text string
+	// Reason explains why the line was skipped.
+	Reason string
+}
+
+// CasbinReport lists the lines skipped by ImportCasbin or ExportCasbin.
+type CasbinReport struct {
+	Skipped []CasbinIssue
+}
+
+func (r *CasbinReport) skip(line int, fields []string, reason string) {
+	r.Skipped = append(r.Skipped, CasbinIssue{Line: line, Text: strings.Join(fields, ", "), Reason: reason})
+}
+
+// ImportCasbin reads Casbin CSV policy lines into `rbac`.
+// `p, role, obj, act` assigns the DeepPermission `obj:act` (or `obj` if `act` is `*`) to the role,
+// `g, child, parent` binds the parent to the child. Missing roles are created.
+// Lines with deny effects, domains, keyMatch or regex patterns in `obj` or `act`,
+// or other policy types are skipped and reported.
+// Lines are applied one by one: if an error is returned, roles and permissions
+// of the lines before the failed one stay imported.
+func ImportCasbin(rbac *RBAC, r io.Reader) (*CasbinReport, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	report := &CasbinReport{}
+	for {
+		fields, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return report, err
+		}
+		line, _ := reader.FieldPos(0)
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+
+		switch fields[0] {
+		case "p":
+			if len(fields) == 5 && fields[4] == "allow" {
+				fields = fields[:4]
+			}
+			if len(fields) == 5 && fields[4] == "deny" {
+				report.skip(line, fields, "deny effect is not supported")
+				continue
+			}
+			if len(fields) != 4 {
+				report.skip(line, fields, "domains and extra fields are not supported")
+				continue
+			}
+			if casbinPattern(fields[2]) || fields[3] != AnyAction && casbinPattern(fields[3]) {
+				report.skip(line, fields, "patterns are not supported")
+				continue
+			}
+			if err := casbinRole(rbac, fields[1]); err != nil {
+				return report, err
+			}
+			id := fields[2]
+			if fields[3] != AnyAction {
+				id += ":" + fields[3]
+			}
+			if err := rbac.Assign(fields[1], NewDeepPermission(id)); err != nil {
+				return report, err
+			}
+		case "g":
+			if len(fields) != 3 {
+				report.skip(line, fields, "domains and extra fields are not supported")
+				continue
+			}
+			for _, id := range fields[1:] {
+				if err := casbinRole(rbac, id); err != nil {
+					return report, err
+				}
+			}
+			if err := rbac.SetParent(fields[1], fields[2]); err != nil {
+				return report, err
+			}
+		default:
+			report.skip(line, fields, "policy type "+fields[0]+" is not supported")
+		}
+	}
+	return report, nil
+}
+
+// casbinPattern returns true if `s` contains wildcards of keyMatch
+// or regular expression syntax, which can't be expressed by a permission id.
+func casbinPattern(s string) bool {
+	return strings.ContainsAny(s, "*?()[]{}|^$+\\")
+}
+
+func casbinRole(rbac *RBAC, id string) error {
+	if err := rbac.Add(NewRole(id)); err != nil && !errors.Is(err, ErrRoleExist) {
+		return err
+	}
+	return nil
+}
+
+// ExportCasbin writes `rbac` as Casbin CSV policy lines sorted by role, quoted as CSV where needed.
+// A DeepPermission `obj:act` is written as `p,role,obj,act`, a single layer `obj` as `p,role,obj,*`.
+// Permissions of other kinds, including resource permissions whose action hierarchy
+// Casbin can't express, and parent edges with inheritance limits or filters are skipped and reported.
+func ExportCasbin(rbac *RBAC, w io.Writer) (*CasbinReport, error) {
+	rbac.mutex.RLock()
+	var roles []string
+	for id := range rbac.roles {
+		roles = append(roles, id)
+	}
+	sort.Strings(roles)
+
+	report := &CasbinReport{}
+	var lines [][]string
+	var edges [][]string
+	for _, id := range roles {
+		permissions := rbac.roles[id].Permissions()
+		sort.Slice(permissions, func(i, j int) bool { return permissions[i].ID() < permissions[j].ID() })
+		for _, p := range permissions {
+			var layers []string
+			switch v := unbind(p).(type) {
+			case *DeepPermission:
+				layers = strings.Split(v.IDStr, v.Sep)
+			case *ResourcePermission:
+				report.skip(0, []string{"p", id, p.ID()}, "action hierarchies of resource permissions are not supported")
+				continue
+			default:
+				report.skip(0, []string{"p", id, p.ID()}, "only layered permissions are supported")
+				continue
+			}
+			obj, act := strings.Join(layers, ":"), AnyAction
+			if n := len(layers); n > 1 {
+				obj, act = strings.Join(layers[:n-1], ":"), layers[n-1]
+			}
+			lines = append(lines, []string{"p", id, obj, act})
+		}
+		var parents []string
+		for parent := range rbac.parents[id] {
+			parents = append(parents, parent)
+		}
+		sort.Strings(parents)
+		for _, parent := range parents {
+			e, ok := rbac.edges[edgeKey{id, parent}]
+			if len(e.filter) > 0 {
+				report.skip(0, []string{"g", id, parent}, "edge filters are not supported")
+				continue
+			}
+			if ok || rbac.depths[id] > 0 {
+				report.skip(0, []string{"g", id, parent}, "inheritance limits are not supported")
+				continue
+			}
+			edges = append(edges, []string{"g", id, parent})
+		}
+	}
+	rbac.mutex.RUnlock()
+
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll(append(lines, edges...)); err != nil {
+		return report, err
+	}
+	return report, nil
+}
@@ -0,0 +1,103 @@
+package gorbac
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+const casbinPolicy = `# roles
+p, reader, data, read
+p, writer, data, write
+p, admin, system, *
+p, writer, data2, write, allow
+p, blocked, data, read, deny
+p, alice, domain1, data, read
+p, bob, /data/*, (GET)|(POST)
+p, bob, data3, GET|POST
+g, writer, reader
+g, admin, writer
+g, bob, admin, domain1
+g2, alice, group
+`
+
+func TestImportCasbin(t *testing.T) {
+	rbac := New()
+	report, err := ImportCasbin(rbac, strings.NewReader(casbinPolicy))
+	assert(t, err)
+
+	if len(report.Skipped) != 6 {
+		t.Fatalf("6 skipped lines expected, but %+v got", report.Skipped)
+	}
+	if issue := report.Skipped[0]; issue.Line != 6 || issue.Text != "p, blocked, data, read, deny" {
+		t.Fatalf("Unexpected issue: %+v", issue)
+	}
+	if issue := report.Skipped[2]; issue.Line != 8 || issue.Reason != "patterns are not supported" {
+		t.Fatalf("Unexpected issue: %+v", issue)
+	}
+
+	if !rbac.IsGranted("admin", NewDeepPermission("data:read"), nil) ||
+		!rbac.IsGranted("admin", NewDeepPermission("system:reboot"), nil) ||
+		!rbac.IsGranted("writer", NewDeepPermission("data2:write"), nil) {
+		t.Fatal("Imported hierarchy should grant permissions")
+	}
+	if rbac.IsGranted("reader", NewDeepPermission("data:write"), nil) {
+		t.Fatal("[reader] should not write")
+	}
+	if _, _, err := rbac.GetRole("blocked"); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatal("Skipped lines should not create roles")
+	}
+}
+
+func TestExportCasbin(t *testing.T) {
+	rbac := New()
+	_, err := ImportCasbin(rbac, strings.NewReader(casbinPolicy))
+	assert(t, err)
+	assert(t, rbac.Assign("reader", NewPermission("simple")))
+
+	var buf bytes.Buffer
+	report, err := ExportCasbin(rbac, &buf)
+	assert(t, err)
+	if len(report.Skipped) != 1 || report.Skipped[0].Text != "p, reader, simple" {
+		t.Fatalf("Unexpected report: %+v", report.Skipped)
+	}
+
+	expected := `p,admin,system,*
+p,reader,data,read
+p,writer,data2,write
+p,writer,data,write
+g,admin,writer
+g,writer,reader
+`
+	if buf.String() != expected {
+		t.Fatalf("Unexpected export:\n%s", buf.String())
+	}
+
+	cp := New()
+	report, err = ImportCasbin(cp, &buf)
+	assert(t, err)
+	if len(report.Skipped) != 0 || !cp.IsGranted("admin", NewDeepPermission("data:read"), nil) {
+		t.Fatal("Exported policy should be importable")
+	}
+}
+
+func TestExportCasbin_Quoting(t *testing.T) {
+	rbac := New()
+	assert(t, rbac.Add(NewRole("x, y").Assign(NewDeepPermission("data:read"))))
+	assert(t, rbac.Add(NewRole("clerk").Assign(NewResourcePermission("invoice", "write"))))
+
+	var buf bytes.Buffer
+	report, err := ExportCasbin(rbac, &buf)
+	assert(t, err)
+	if len(report.Skipped) != 1 || report.Skipped[0].Text != "p, clerk, invoice:write" {
+		t.Fatalf("Resource permissions should be reported: %+v", report.Skipped)
+	}
+
+	cp := New()
+	report, err = ImportCasbin(cp, &buf)
+	assert(t, err)
+	if len(report.Skipped) != 0 || !cp.IsGranted("x, y", NewDeepPermission("data:read"), nil) {
+		t.Fatalf("Quoted role should be imported: %+v", report.Skipped)
+	}
+}
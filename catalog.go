@@ -0,0 +1,200 @@
+package gorbac
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrPermissionUndeclared occurred if a permission is missing in the catalog of a strict RBAC
+var ErrPermissionUndeclared = errors.New("permission is not declared")
+
+// maxUnknown limits the number of distinct undeclared permissions a Catalog remembers.
+const maxUnknown = 1024
+
+// CatalogEntry declares a valid permission id.
+type CatalogEntry struct {
+	ID string `json:"id"`
+	// Group collects related permissions, e.g. for an admin UI.
+	Group string `json:"group,omitempty"`
+	Metadata
+}
+
+// Catalog declares every valid permission id.
+// Ids are compared exactly, so an upper layer of a DeepPermission
+// has to be declared on its own to be assigned in strict mode.
+type Catalog struct {
+	mutex   sync.RWMutex
+	entries map[string]CatalogEntry
+	unknown map[string]uint64
+}
+
+// NewCatalog returns a catalog declaring `entries`.
+func NewCatalog(entries ...CatalogEntry) *Catalog {
+	c := &Catalog{
+		entries: make(map[string]CatalogEntry),
+		unknown: make(map[string]uint64),
+	}
+	c.Declare(entries...)
+	return c
+}
+
+// Declare adds `entries`, an entry with a declared id replaces the previous one.
+func (c *Catalog) Declare(entries ...CatalogEntry) {
+	c.mutex.Lock()
+	for _, e := range entries {
+		e.Metadata = e.Metadata.clone()
+		c.entries[e.ID] = e
+		delete(c.unknown, e.ID)
+	}
+	c.mutex.Unlock()
+}
+
+// Lookup returns the entry of the permission id.
+func (c *Catalog) Lookup(id string) (CatalogEntry, bool) {
+	c.mutex.RLock()
+	e, ok := c.entries[id]
+	c.mutex.RUnlock()
+	return e, ok
+}
+
+// Declared returns true if the id of `p` is declared.
+func (c *Catalog) Declared(p Permission) bool {
+	if p == nil {
+		return false
+	}
+	_, ok := c.Lookup(p.ID())
+	return ok
+}
+
+// Entries returns all entries sorted by id.
+func (c *Catalog) Entries() []CatalogEntry {
+	c.mutex.RLock()
+	result := make([]CatalogEntry, 0, len(c.entries))
+	for _, e := range c.entries {
+		result = append(result, e)
+	}
+	c.mutex.RUnlock()
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result
+}
+
+// Groups returns entries by their group, each sorted by id.
+func (c *Catalog) Groups() map[string][]CatalogEntry {
+	result := make(map[string][]CatalogEntry)
+	for _, e := range c.Entries() {
+		result[e.Group] = append(result[e.Group], e)
+	}
+	return result
+}
+
+// Unknown returns undeclared permission ids checked through RBAC with the number of checks.
+// At most 1024 distinct ids are remembered.
+func (c *Catalog) Unknown() map[string]uint64 {
+	c.mutex.RLock()
+	result := make(map[string]uint64, len(c.unknown))
+	for id, n := range c.unknown {
+		result[id] = n
+	}
+	c.mutex.RUnlock()
+	return result
+}
+
+// ResetUnknown forgets undeclared permissions checked so far.
+func (c *Catalog) ResetUnknown() {
+	c.mutex.Lock()
+	c.unknown = make(map[string]uint64)
+	c.mutex.Unlock()
+}
+
+// observe remembers `p` if it is undeclared.
+func (c *Catalog) observe(p Permission) {
+	id := p.ID()
+	c.mutex.RLock()
+	_, ok := c.entries[id]
+	c.mutex.RUnlock()
+	if ok {
+		return
+	}
+	c.mutex.Lock()
+	if _, seen := c.unknown[id]; seen || len(c.unknown) < maxUnknown {
+		c.unknown[id]++
+	}
+	c.mutex.Unlock()
+}
+
+// Undeclared returns sorted ids of undeclared permissions assigned to roles of `rbac`, keyed by role.
+func (c *Catalog) Undeclared(rbac *RBAC) map[string][]string {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	result := make(map[string][]string)
+	for id, role := range rbac.roles {
+		for _, p := range role.Permissions() {
+			if !c.Declared(p) {
+				result[id] = append(result[id], p.ID())
+			}
+		}
+		sort.Strings(result[id])
+	}
+	return result
+}
+
+// SetCatalog registers the catalog `c` on `rbac`, undeclared permissions checked
+// by IsGranted, AnyGranted, AllGranted, CheckMany and Query are reported by c.Unknown.
+// In `strict` mode Assign, Add, Import, Merge and Replace refuse undeclared permissions.
+// A nil `c` removes the catalog.
+func (rbac *RBAC) SetCatalog(c *Catalog, strict bool) {
+	rbac.mutex.Lock()
+	rbac.catalog = c
+	rbac.strict = strict && c != nil
+	rbac.mutex.Unlock()
+}
+
+// Catalog returns the registered catalog, if any.
+func (rbac *RBAC) Catalog() *Catalog {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	return rbac.catalog
+}
+
+// observe reports a checked permission to the catalog.
+// The lock has to be held by the caller.
+func (rbac *RBAC) observe(p Permission) {
+	if rbac.catalog != nil && p != nil {
+		rbac.catalog.observe(p)
+	}
+}
+
+// declared returns an error if any of `perms` can't be assigned in strict mode.
+// The lock has to be held by the caller.
+func (rbac *RBAC) declared(perms ...Permission) error {
+	if !rbac.strict {
+		return nil
+	}
+	var ids []string
+	for _, p := range perms {
+		if !rbac.catalog.Declared(p) {
+			ids = append(ids, p.ID())
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	sort.Strings(ids)
+	return fmt.Errorf("%w: %s", ErrPermissionUndeclared, strings.Join(ids, ", "))
+}
+
+// declaredRoles returns an error if any permission of `roles` can't be assigned in strict mode.
+// The lock has to be held by the caller.
+func (rbac *RBAC) declaredRoles(roles Roles) error {
+	if !rbac.strict {
+		return nil
+	}
+	var perms []Permission
+	for _, role := range roles {
+		perms = append(perms, role.Permissions()...)
+	}
+	return rbac.declared(perms...)
+}
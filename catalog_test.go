@@ -0,0 +1,125 @@
+package gorbac
+
+import (
+	"errors"
+	"testing"
+)
+
+func prepareCatalog() *Catalog {
+	return NewCatalog(
+		CatalogEntry{ID: "task", Group: "tasks"},
+		CatalogEntry{ID: "task:read", Group: "tasks", Metadata: Metadata{Description: "Read tasks"}},
+		CatalogEntry{ID: "task:create", Group: "tasks"},
+		CatalogEntry{ID: "user", Group: "users"},
+		CatalogEntry{ID: "system", Group: "system"},
+	)
+}
+
+func TestCatalog(t *testing.T) {
+	c := prepareCatalog()
+
+	if e, ok := c.Lookup("task:read"); !ok || e.Description != "Read tasks" {
+		t.Fatalf("Unexpected entry: %+v", e)
+	}
+	if !c.Declared(NewDeepPermission("task")) || c.Declared(NewDeepPermission("tsak:read")) || c.Declared(nil) {
+		t.Fatal("Declared works incorrectly")
+	}
+	if groups := c.Groups(); len(groups["tasks"]) != 3 || groups["tasks"][0].ID != "task" {
+		t.Fatalf("Unexpected groups: %+v", groups)
+	}
+
+	rbac := prepareTree(t)
+	assert(t, rbac.Assign("observer", NewDeepPermission("tsak:read")))
+	undeclared := c.Undeclared(rbac)
+	if len(undeclared) != 1 || !equalStrings(undeclared["observer"], []string{"tsak:read"}) {
+		t.Fatalf("Unexpected undeclared permissions: %v", undeclared)
+	}
+}
+
+func TestRBAC_StrictCatalog(t *testing.T) {
+	rbac := prepareTree(t)
+	c := prepareCatalog()
+	rbac.SetCatalog(c, true)
+
+	if err := rbac.Assign("observer", NewDeepPermission("tsak:read")); !errors.Is(err, ErrPermissionUndeclared) {
+		t.Fatalf("%s needed, but %v got", ErrPermissionUndeclared, err)
+	}
+	assert(t, rbac.Assign("observer", NewDeepPermission("task:create")))
+
+	rbac.SetCatalog(c, false)
+	assert(t, rbac.Assign("observer", NewDeepPermission("tsak:read")))
+	if rbac.Catalog() != c {
+		t.Fatal("Catalog should be registered")
+	}
+}
+
+func TestRBAC_StrictCatalogBulk(t *testing.T) {
+	rbac := New()
+	assert(t, rbac.Add(NewRole("legacy").Assign(NewDeepPermission("tsak:read"))))
+	assert(t, rbac.Add(NewRole("reader").Assign(NewDeepPermission("task:read"))))
+	rbac.SetCatalog(prepareCatalog(), true)
+
+	if err := rbac.Add(NewRole("writer").Assign(NewDeepPermission("tsak:read"))); !errors.Is(err, ErrPermissionUndeclared) {
+		t.Fatalf("%s needed, but %v got", ErrPermissionUndeclared, err)
+	}
+	if _, _, err := rbac.GetRole("writer"); err == nil {
+		t.Fatal("Refused role should not be added")
+	}
+	assert(t, rbac.Add(NewRole("writer").Assign(NewDeepPermission("task:create"))))
+
+	policy := &Policy{Roles: []RoleRecord{{ID: "guest", Permissions: []PermissionRecord{{ID: "tsak", Kind: KindDeep}}}}}
+	if err := rbac.Import(policy); !errors.Is(err, ErrPermissionUndeclared) {
+		t.Fatalf("%s needed, but %v got", ErrPermissionUndeclared, err)
+	}
+	if err := rbac.Merge("legacy", "reader"); !errors.Is(err, ErrPermissionUndeclared) {
+		t.Fatalf("%s needed, but %v got", ErrPermissionUndeclared, err)
+	}
+	if rbac.IsGranted("reader", NewDeepPermission("tsak:read"), nil) {
+		t.Fatal("Refused merge should leave roles unchanged")
+	}
+
+	other := New()
+	assert(t, other.Add(NewRole("guest").Assign(NewDeepPermission("tsak"))))
+	if err := rbac.Replace(other); !errors.Is(err, ErrPermissionUndeclared) {
+		t.Fatalf("%s needed, but %v got", ErrPermissionUndeclared, err)
+	}
+	if _, _, err := rbac.GetRole("writer"); err != nil {
+		t.Fatal("Refused replace should leave roles unchanged")
+	}
+	other = New()
+	assert(t, other.Add(NewRole("guest").Assign(NewDeepPermission("task"))))
+	assert(t, rbac.Replace(other))
+}
+
+func TestRBAC_UnknownPermissions(t *testing.T) {
+	rbac := prepareTree(t)
+	c := prepareCatalog()
+	rbac.SetCatalog(c, false)
+
+	rbac.IsGranted("observer", NewDeepPermission("task:read"), nil)
+	rbac.IsGranted("observer", NewDeepPermission("tsak:read"), nil)
+	rbac.IsGranted("admin", NewDeepPermission("tsak:read"), nil)
+	AnyGranted(rbac, []string{"admin"}, NewDeepPermission("user:delete"), nil)
+	AllGranted(rbac, []string{"admin"}, NewDeepPermission("user"), nil)
+	rbac.CheckMany([]string{"admin"}, []Permission{NewDeepPermission("system"), NewPermission("stats")}, nil)
+
+	unknown := c.Unknown()
+	if len(unknown) != 3 || unknown["tsak:read"] != 2 || unknown["user:delete"] != 1 || unknown["stats"] != 1 {
+		t.Fatalf("Unexpected unknown permissions: %v", unknown)
+	}
+
+	c.Declare(CatalogEntry{ID: "stats"})
+	if _, ok := c.Unknown()["stats"]; ok {
+		t.Fatal("Declared permission should not be unknown")
+	}
+	c.ResetUnknown()
+	if len(c.Unknown()) != 0 {
+		t.Fatal("Unknown permissions should be reset")
+	}
+
+	rbac.SetCatalog(nil, true)
+	rbac.IsGranted("observer", NewDeepPermission("tsak:read"), nil)
+	if len(c.Unknown()) != 0 {
+		t.Fatal("Removed catalog should not be reported to")
+	}
+}
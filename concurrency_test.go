@@ -0,0 +1,187 @@
+package gorbac
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRBAC_ConcurrentChecksAndMutations(t *testing.T) {
+	rbac := prepareTree(t)
+	roles := []string{"observer", "reporter", "moderator"}
+	p := NewDeepPermission("system:shutdown")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			id := "temp-" + strconv.Itoa(i)
+			for j := 0; j < 200; j++ {
+				if err := rbac.Add(NewRole(id)); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := rbac.SetParent(id, "moderator"); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := rbac.Assign(id, NewPermission("temp")); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := rbac.Remove(id); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}(i)
+	}
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if !AnyGranted(rbac, roles, p, nil) || !AllGranted(rbac, roles, p, nil) {
+					t.Error("Stable roles should be granted")
+					return
+				}
+				if !rbac.IsGranted("observer", p, nil) {
+					t.Error("observer should be granted")
+					return
+				}
+				if _, err := rbac.GetParents("observer"); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := InheritanceCircle(rbac); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := Walk(rbac, func(Role, []string) error { return nil }); err != nil {
+					t.Error(err)
+					return
+				}
+				if err := WalkContext(context.Background(), rbac, func(Role, []string) error { return nil }); err != nil {
+					t.Error(err)
+					return
+				}
+				rbac.CheckMany(roles, []Permission{p}, nil)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestRBAC_EventOrder(t *testing.T) {
+	rbac := prepareTree(t)
+	replica := prepareTree(t)
+	rbac.OnChange(func(e Event) {
+		if err := replica.Apply(e); err != nil {
+			t.Error(err)
+		}
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				if err := rbac.SetParent("observer", "admin"); err != nil {
+					t.Error(err)
+				}
+				if err := rbac.RemoveParent("observer", "admin"); err != nil {
+					t.Error(err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	expected, err := rbac.Export()
+	assert(t, err)
+	got, err := replica.Export()
+	assert(t, err)
+	if !equalPolicies(expected, got) {
+		t.Fatalf("Events should be delivered in order:\n%+v\n%+v", expected, got)
+	}
+}
+
+func TestRBAC_ReentrantHandler(t *testing.T) {
+	rbac := New()
+	var ops []EventOp
+	rbac.OnChange(func(e Event) {
+		ops = append(ops, e.Op)
+		if e.Op == EventAddRole {
+			if err := rbac.AssignSubject("owner", e.Role); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+	assert(t, rbac.Add(NewRole("a")))
+	assert(t, rbac.Assign("a", NewPermission("x")))
+	if len(ops) != 3 || ops[0] != EventAddRole || ops[1] != EventAssignSubject || ops[2] != EventAssign {
+		t.Fatalf("Unexpected events: %v", ops)
+	}
+}
+
+func TestRBAC_SharedReadLock(t *testing.T) {
+	rbac := prepareTree(t)
+	roles := []string{"observer", "reporter"}
+	p := NewDeepPermission("task:read")
+
+	// checks in another goroutine finish while Walk holds the lock,
+	// which wouldn't happen if any of them required the exclusive lock
+	assert(t, Walk(rbac, func(r Role, _ []string) error {
+		done := make(chan struct{})
+		go func() {
+			AnyGranted(rbac, roles, p, nil)
+			AllGranted(rbac, roles, p, nil)
+			rbac.GetParents(r.ID())
+			InheritanceCircle(rbac)
+			Walk(rbac, func(Role, []string) error { return nil })
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("Read paths are blocked by Walk")
+		}
+		return nil
+	}))
+}
+
+func benchmarkParallel(b *testing.B, check func(rbac *RBAC)) {
+	rbac := largeRBAC(b, 1000)
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			check(rbac)
+		}
+	})
+}
+
+func BenchmarkAnyGrantedParallel(b *testing.B) {
+	roles := []string{"role-998", "role-999"}
+	p := NewDeepPermission("resource-0:write")
+	benchmarkParallel(b, func(rbac *RBAC) {
+		AnyGranted(rbac, roles, p, nil)
+	})
+}
+
+func BenchmarkAllGrantedParallel(b *testing.B) {
+	roles := []string{"role-998", "role-999"}
+	p := NewDeepPermission("resource-0:write")
+	benchmarkParallel(b, func(rbac *RBAC) {
+		AllGranted(rbac, roles, p, nil)
+	})
+}
+
+func BenchmarkGetParentsParallel(b *testing.B) {
+	benchmarkParallel(b, func(rbac *RBAC) {
+		rbac.GetParents("role-999")
+	})
+}
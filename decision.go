@@ -0,0 +1,184 @@
+package gorbac
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AssertionOutcome describes how the AssertionFunc of a check behaved.
+type AssertionOutcome int
+
+const (
+	// AssertionNone means no AssertionFunc was supplied.
+	AssertionNone AssertionOutcome = iota
+	// AssertionPassed means the AssertionFunc returned true.
+	AssertionPassed
+	// AssertionFailed means the AssertionFunc returned false and denied the check.
+	AssertionFailed
+)
+
+// String returns the name of the outcome.
+func (o AssertionOutcome) String() string {
+	switch o {
+	case AssertionPassed:
+		return "passed"
+	case AssertionFailed:
+		return "failed"
+	}
+	return "none"
+}
+
+// MarshalText encodes the outcome by its name.
+func (o AssertionOutcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// Decision is a record of a single authorization check.
+type Decision struct {
+	// Time is the moment the check started.
+	Time time.Time `json:"time"`
+	// Op is the name of the check: IsGranted, AnyGranted, AllGranted, CheckMany, Query or Filter.
+	// Checks of an EffectiveSet are not logged.
+	Op string `json:"op"`
+	// Roles are the roles the check was made for.
+	Roles []string `json:"roles"`
+	// Permission is the id of the checked permission.
+	Permission string `json:"permission"`
+	// Granted is the result of the check.
+	Granted bool `json:"granted"`
+	// Role is the role which decided the check: the role granting the permission,
+	// or, for AllGranted, the first role being denied.
+	Role string `json:"role,omitempty"`
+	// Matched is the id of the role's permission which granted the check.
+	Matched string `json:"matched,omitempty"`
+	// Assertion is the outcome of the AssertionFunc of the deciding role.
+	Assertion AssertionOutcome `json:"assertion"`
+	// Latency is the time the check took.
+	Latency time.Duration `json:"latency"`
+}
+
+func newDecision(op string, roles []string, p Permission) Decision {
+	d := Decision{
+		Time:  time.Now(),
+		Op:    op,
+		Roles: roles,
+	}
+	if p != nil {
+		d.Permission = p.ID()
+	}
+	return d
+}
+
+func (d *Decision) finish(granted bool) {
+	d.Granted = granted
+	d.Latency = time.Since(d.Time)
+}
+
+// DecisionLogger receives records of authorization decisions.
+// It is called outside of the RBAC lock, but may be called concurrently.
+type DecisionLogger interface {
+	LogDecision(Decision)
+}
+
+// DecisionLoggerFunc is an adapter to use an ordinary function as DecisionLogger.
+type DecisionLoggerFunc func(Decision)
+
+// LogDecision calls f(d).
+func (f DecisionLoggerFunc) LogDecision(d Decision) {
+	f(d)
+}
+
+// NewSlogDecisionLogger returns a DecisionLogger writing decisions to `l`.
+// Granted decisions are logged at the info level, denied ones at the warn level.
+func NewSlogDecisionLogger(l *slog.Logger) DecisionLogger {
+	return DecisionLoggerFunc(func(d Decision) {
+		level := slog.LevelInfo
+		if !d.Granted {
+			level = slog.LevelWarn
+		}
+		l.LogAttrs(context.Background(), level, "authorization decision",
+			slog.Time("time", d.Time),
+			slog.String("op", d.Op),
+			slog.Any("roles", d.Roles),
+			slog.String("permission", d.Permission),
+			slog.Bool("granted", d.Granted),
+			slog.String("role", d.Role),
+			slog.String("matched", d.Matched),
+			slog.String("assertion", d.Assertion.String()),
+			slog.Duration("latency", d.Latency),
+		)
+	})
+}
+
+// JSONDecisionLogger writes every decision as a JSON line.
+type JSONDecisionLogger struct {
+	mutex sync.Mutex
+	enc   *json.Encoder
+	err   error
+}
+
+// NewJSONDecisionLogger returns a DecisionLogger writing JSON lines to `w`.
+func NewJSONDecisionLogger(w io.Writer) *JSONDecisionLogger {
+	return &JSONDecisionLogger{enc: json.NewEncoder(w)}
+}
+
+// LogDecision writes `d` as a single line.
+func (l *JSONDecisionLogger) LogDecision(d Decision) {
+	l.mutex.Lock()
+	if err := l.enc.Encode(d); err != nil && l.err == nil {
+		l.err = err
+	}
+	l.mutex.Unlock()
+}
+
+// Err returns the first error occurred while writing.
+func (l *JSONDecisionLogger) Err() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.err
+}
+
+// SampleDecisions passes one of every `n` decisions to `next`.
+// If `n` is less than 2, all decisions are passed.
+func SampleDecisions(next DecisionLogger, n uint64) DecisionLogger {
+	if n < 2 {
+		return next
+	}
+	var counter uint64
+	return DecisionLoggerFunc(func(d Decision) {
+		if (atomic.AddUint64(&counter, 1)-1)%n == 0 {
+			next.LogDecision(d)
+		}
+	})
+}
+
+// FilterDecisions passes granted decisions to `next` if `allow` is set
+// and denied decisions if `deny` is set.
+func FilterDecisions(next DecisionLogger, allow, deny bool) DecisionLogger {
+	return DecisionLoggerFunc(func(d Decision) {
+		if (d.Granted && allow) || (!d.Granted && deny) {
+			next.LogDecision(d)
+		}
+	})
+}
+
+// MultiDecisionLogger passes every decision to all of `loggers`.
+// Nil loggers are skipped.
+func MultiDecisionLogger(loggers ...DecisionLogger) DecisionLogger {
+	list := make([]DecisionLogger, 0, len(loggers))
+	for _, l := range loggers {
+		if l != nil {
+			list = append(list, l)
+		}
+	}
+	return DecisionLoggerFunc(func(d Decision) {
+		for _, l := range list {
+			l.LogDecision(d)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package gorbac
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func prepareDecisions(t *testing.T) (*RBAC, *[]Decision) {
+	rbac := New()
+
+	r1 := NewRole("decision-1")
+	r2 := NewRole("decision-2")
+	r1.Assign(NewDeepPermission("task"))
+	r2.Assign(NewDeepPermission("user:read"))
+
+	assert(t, rbac.Add(r1))
+	assert(t, rbac.Add(r2))
+	assert(t, rbac.SetParent("decision-2", "decision-1"))
+
+	var list []Decision
+	rbac.SetDecisionLogger(DecisionLoggerFunc(func(d Decision) {
+		list = append(list, d)
+	}))
+	return rbac, &list
+}
+
+func TestRBAC_DecisionLogger(t *testing.T) {
+	rbac, list := prepareDecisions(t)
+
+	if !rbac.IsGranted("decision-2", NewDeepPermission("task:read"), nil) {
+		t.Fatal("decision-2 should inherit task:read")
+	}
+	if rbac.IsGranted("decision-1", NewDeepPermission("user:read"), nil) {
+		t.Fatal("decision-1 should not have user:read")
+	}
+	if rbac.IsGranted("decision-2", NewDeepPermission("user:read"), func(*RBAC, string, Permission) bool { return false }) {
+		t.Fatal("Assertion don't work")
+	}
+
+	if len(*list) != 3 {
+		t.Fatalf("3 decisions expected, but %d got", len(*list))
+	}
+
+	d := (*list)[0]
+	if d.Op != "IsGranted" || !d.Granted || d.Role != "decision-1" || d.Matched != "task" ||
+		d.Permission != "task:read" || d.Assertion != AssertionNone || d.Time.IsZero() {
+		t.Fatalf("Unexpected decision: %+v", d)
+	}
+
+	d = (*list)[1]
+	if d.Granted || d.Role != "" || d.Matched != "" {
+		t.Fatalf("Unexpected decision: %+v", d)
+	}
+
+	d = (*list)[2]
+	if d.Granted || d.Assertion != AssertionFailed {
+		t.Fatalf("Unexpected decision: %+v", d)
+	}
+
+	rbac.SetDecisionLogger(nil)
+	rbac.IsGranted("decision-2", NewDeepPermission("task:read"), nil)
+	if len(*list) != 3 {
+		t.Fatal("Logger should be disabled")
+	}
+}
+
+func TestAnyAllGranted_DecisionLogger(t *testing.T) {
+	rbac, list := prepareDecisions(t)
+	roles := []string{"decision-1", "decision-2"}
+
+	if !AnyGranted(rbac, roles, NewDeepPermission("user:read"), nil) {
+		t.Fatal("decision-2 should have user:read")
+	}
+	if AllGranted(rbac, roles, NewDeepPermission("user:read"), nil) {
+		t.Fatal("decision-1 should not have user:read")
+	}
+	if !AllGranted(rbac, roles, NewDeepPermission("task"), nil) {
+		t.Fatal("All roles should have task")
+	}
+
+	if d := (*list)[0]; d.Op != "AnyGranted" || !d.Granted || d.Role != "decision-2" || len(d.Roles) != 2 {
+		t.Fatalf("Unexpected decision: %+v", d)
+	}
+	if d := (*list)[1]; d.Op != "AllGranted" || d.Granted || d.Role != "decision-1" {
+		t.Fatalf("Unexpected decision: %+v", d)
+	}
+	if d := (*list)[2]; !d.Granted || d.Role != "" {
+		t.Fatalf("Unexpected decision: %+v", d)
+	}
+}
+
+func TestJSONDecisionLogger(t *testing.T) {
+	rbac, _ := prepareDecisions(t)
+
+	var buf bytes.Buffer
+	l := NewJSONDecisionLogger(&buf)
+	rbac.SetDecisionLogger(l)
+
+	rbac.IsGranted("decision-2", NewDeepPermission("task:read"), func(*RBAC, string, Permission) bool { return true })
+	rbac.IsGranted("decision-1", NewDeepPermission("user:read"), nil)
+	assert(t, l.Err())
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("2 lines expected, but %d got", len(lines))
+	}
+
+	var record map[string]interface{}
+	assert(t, json.Unmarshal([]byte(lines[0]), &record))
+	if record["granted"] != true || record["assertion"] != "passed" || record["matched"] != "task" {
+		t.Fatalf("Unexpected record: %s", lines[0])
+	}
+}
+
+func TestSlogDecisionLogger(t *testing.T) {
+	rbac, _ := prepareDecisions(t)
+
+	var buf bytes.Buffer
+	rbac.SetDecisionLogger(NewSlogDecisionLogger(slog.New(slog.NewTextHandler(&buf, nil))))
+
+	rbac.IsGranted("decision-1", NewDeepPermission("user:read"), nil)
+	if out := buf.String(); !strings.Contains(out, "level=WARN") || !strings.Contains(out, "permission=user:read") {
+		t.Fatalf("Unexpected output: %s", out)
+	}
+}
+
+func TestSampleFilterDecisions(t *testing.T) {
+	var granted, denied int
+	counter := DecisionLoggerFunc(func(d Decision) {
+		if d.Granted {
+			granted++
+		} else {
+			denied++
+		}
+	})
+
+	l := SampleDecisions(counter, 3)
+	for i := 0; i < 9; i++ {
+		l.LogDecision(Decision{Granted: true})
+	}
+	if granted != 3 {
+		t.Fatalf("3 sampled decisions expected, but %d got", granted)
+	}
+
+	l = FilterDecisions(counter, false, true)
+	l.LogDecision(Decision{Granted: true})
+	l.LogDecision(Decision{Granted: false})
+	if granted != 3 || denied != 1 {
+		t.Fatalf("Only denied decisions expected, but %d/%d got", granted, denied)
+	}
+}
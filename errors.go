@@ -0,0 +1,31 @@
+package gorbac
+
+import "strings"
+
+// RoleError describes a failed operation and the roles it failed on.
+// It wraps ErrRoleNotExist or ErrRoleExist, so it can be tested by errors.Is.
+type RoleError struct {
+	// Op is the name of the operation, e.g. SetParent.
+	Op string
+	// IDs are the offending roles.
+	IDs []string
+	// Err is the reason.
+	Err error
+}
+
+func (e *RoleError) Error() string {
+	return e.Op + " " + strings.Join(e.IDs, ", ") + ": " + e.Err.Error()
+}
+
+// Unwrap returns the reason.
+func (e *RoleError) Unwrap() error {
+	return e.Err
+}
+
+func roleNotExist(op string, ids ...string) error {
+	return &RoleError{Op: op, IDs: ids, Err: ErrRoleNotExist}
+}
+
+func roleExist(op string, ids ...string) error {
+	return &RoleError{Op: op, IDs: ids, Err: ErrRoleExist}
+}
@@ -0,0 +1,246 @@
+package gorbac
+
+import "errors"
+
+// ErrRoleImmutable occurred if permissions of a role can't be changed through RBAC
+var ErrRoleImmutable = errors.New("role does not support permission changes")
+
+// EventOp is the kind of a change of RBAC.
+type EventOp string
+
+// Kinds of changes.
+const (
+	EventAddRole         EventOp = "add_role"
+	EventRemoveRole      EventOp = "remove_role"
+	EventSetParent       EventOp = "set_parent"
+	EventRemoveParent    EventOp = "remove_parent"
+	EventAssign          EventOp = "assign"
+	EventRevoke          EventOp = "revoke"
+	EventAssignSubject   EventOp = "assign_subject"
+	EventUnassignSubject EventOp = "unassign_subject"
+	EventSetMetadata     EventOp = "set_metadata"
+	EventSetDepth        EventOp = "set_depth"
+)
+
+// Event describes a single change of RBAC.
+// EventAddRole carries all permissions of the added role,
+// EventAssign and EventRevoke carry the single changed permission.
+// EventAddRole and EventSetMetadata carry metadata of the role, if any.
+// EventSetParent carries the inheritance depth and the filter permissions of the edge,
+// EventSetDepth the inheritance depth of the role.
+type Event struct {
+	Op          EventOp            `json:"op"`
+	Role        string             `json:"role"`
+	Parent      string             `json:"parent,omitempty"`
+	Subject     string             `json:"subject,omitempty"`
+	Permissions []PermissionRecord `json:"permissions,omitempty"`
+	Metadata    *Metadata          `json:"metadata,omitempty"`
+	Depth       int                `json:"depth,omitempty"`
+}
+
+// ChangeHandler is a function defined by user to handle changes of RBAC.
+// It is called after the change is made, outside of the RBAC lock.
+// Events are delivered one at a time in the order the changes were made,
+// concurrent changes may be delivered by the goroutine which made an earlier one.
+type ChangeHandler func(Event)
+
+// OnChange registers `h` to be called on every change made by
+// Add, Remove, RemoveWith, Rename, Merge, SetParent, SetParents, RemoveParent,
+// Assign, Revoke, AssignSubject, UnassignSubject, SetMetadata, SetInheritDepth, Import and Apply.
+// Permissions changed directly on a role are not reported.
+func (rbac *RBAC) OnChange(h ChangeHandler) {
+	if h == nil {
+		return
+	}
+	rbac.mutex.Lock()
+	rbac.handlers = append(rbac.handlers, h)
+	rbac.mutex.Unlock()
+}
+
+// watched returns true if any ChangeHandler is registered.
+// The lock has to be held by the caller.
+func (rbac *RBAC) watched() bool {
+	return len(rbac.handlers) > 0
+}
+
+// emit queues `events` for the registered handlers in the order the changes are made.
+// The write lock has to be held by the caller, notify has to be called after unlocking.
+func (rbac *RBAC) emit(events ...Event) {
+	if rbac.watched() {
+		rbac.queue = append(rbac.queue, events...)
+	}
+}
+
+// notify passes queued events to the handlers.
+// Only one goroutine delivers at a time, so handlers see events in the order of emit;
+// events queued while another goroutine delivers are delivered by it.
+func (rbac *RBAC) notify() {
+	for rbac.delivering.TryLock() {
+		for {
+			rbac.mutex.Lock()
+			events, handlers := rbac.queue, rbac.handlers
+			rbac.queue = nil
+			rbac.mutex.Unlock()
+			if len(events) == 0 {
+				break
+			}
+			for _, e := range events {
+				for _, h := range handlers {
+					h(e)
+				}
+			}
+		}
+		rbac.delivering.Unlock()
+
+		rbac.mutex.RLock()
+		pending := len(rbac.queue)
+		rbac.mutex.RUnlock()
+		if pending == 0 {
+			return
+		}
+	}
+}
+
+// roleMetadata returns metadata of `r` for an event, nil if there is none.
+func roleMetadata(r Role) *Metadata {
+	m := MetadataOf(r)
+	if m.IsZero() {
+		return nil
+	}
+	return &m
+}
+
+// permissionRecords returns records of permissions the kind of which is known.
+func permissionRecords(list []Permission) []PermissionRecord {
+	var records []PermissionRecord
+	for _, p := range list {
+		if r, err := NewPermissionRecord(p); err == nil {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+type mutableRole interface {
+	Assign(Permission) *SimpleRole
+	Revoke(Permission) error
+}
+
+// Assign a permission to the role `id`.
+// The role has to implement Assign and Revoke as SimpleRole does.
+// If a strict catalog is set, `p` has to be declared in it.
+func (rbac *RBAC) Assign(id string, p Permission) error {
+	rbac.mutex.Lock()
+	role, ok := rbac.roles[id]
+	if !ok {
+		rbac.mutex.Unlock()
+		return roleNotExist("Assign", id)
+	}
+	if err := rbac.declared(p); err != nil {
+		rbac.mutex.Unlock()
+		return err
+	}
+	mr, ok := role.(mutableRole)
+	if !ok {
+		rbac.mutex.Unlock()
+		return ErrRoleImmutable
+	}
+	mr.Assign(p)
+	if rbac.watched() {
+		rbac.emit(Event{Op: EventAssign, Role: id, Permissions: permissionRecords([]Permission{p})})
+	}
+	rbac.mutex.Unlock()
+	rbac.notify()
+	return nil
+}
+
+// Revoke the permission from the role `id`.
+func (rbac *RBAC) Revoke(id string, p Permission) error {
+	rbac.mutex.Lock()
+	role, ok := rbac.roles[id]
+	if !ok {
+		rbac.mutex.Unlock()
+		return roleNotExist("Revoke", id)
+	}
+	mr, ok := role.(mutableRole)
+	if !ok {
+		rbac.mutex.Unlock()
+		return ErrRoleImmutable
+	}
+	err := mr.Revoke(p)
+	if err == nil && rbac.watched() {
+		rbac.emit(Event{Op: EventRevoke, Role: id, Permissions: permissionRecords([]Permission{p})})
+	}
+	rbac.mutex.Unlock()
+	if err != nil {
+		return err
+	}
+	rbac.notify()
+	return nil
+}
+
+// Apply replays `events` on `rbac`, e.g. ones received from another instance.
+// It stops at the first failed event.
+func (rbac *RBAC) Apply(events ...Event) error {
+	for _, e := range events {
+		var err error
+		switch e.Op {
+		case EventAddRole:
+			role := NewRole(e.Role)
+			for _, r := range e.Permissions {
+				p, perr := r.Permission()
+				if perr != nil {
+					return perr
+				}
+				role.Assign(p)
+			}
+			if e.Metadata != nil {
+				role.SetMetadata(*e.Metadata)
+			}
+			err = rbac.Add(role)
+		case EventRemoveRole:
+			err = rbac.Remove(e.Role)
+		case EventSetParent:
+			filter, ferr := recordPermissions(e.Permissions)
+			if ferr != nil {
+				return ferr
+			}
+			err = rbac.SetParent(e.Role, e.Parent, InheritDepth(e.Depth), InheritOnly(filter...))
+		case EventRemoveParent:
+			err = rbac.RemoveParent(e.Role, e.Parent)
+		case EventAssign, EventRevoke:
+			for _, r := range e.Permissions {
+				p, perr := r.Permission()
+				if perr != nil {
+					return perr
+				}
+				if e.Op == EventAssign {
+					err = rbac.Assign(e.Role, p)
+				} else {
+					err = rbac.Revoke(e.Role, p)
+				}
+				if err != nil {
+					break
+				}
+			}
+		case EventAssignSubject:
+			err = rbac.AssignSubject(e.Subject, e.Role)
+		case EventUnassignSubject:
+			err = rbac.UnassignSubject(e.Subject, e.Role)
+		case EventSetMetadata:
+			var m Metadata
+			if e.Metadata != nil {
+				m = *e.Metadata
+			}
+			err = rbac.SetMetadata(e.Role, m)
+		case EventSetDepth:
+			err = rbac.SetInheritDepth(e.Role, e.Depth)
+		default:
+			err = errors.New("unknown event: " + string(e.Op))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
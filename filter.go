@@ -0,0 +1,160 @@
+package gorbac
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// EffectiveSet is the resolved set of permissions of some roles including inherited ones.
+// It answers checks without walking the hierarchy, but doesn't see later changes of RBAC.
+// Its checks are reported to the catalog of RBAC, but not to the decision logger.
+type EffectiveSet struct {
+	byID    map[string][]Permission
+	others  []Permission
+	catalog *Catalog
+	metrics Metrics
+}
+
+// Effective resolves permissions of `roles` and all their ancestors.
+func (rbac *RBAC) Effective(roles ...string) *EffectiveSet {
+	set := &EffectiveSet{byID: make(map[string][]Permission)}
+
+	rbac.mutex.RLock()
+	set.catalog, set.metrics = rbac.catalog, rbac.metrics
+	for _, p := range rbac.resolve(roles...) {
+		set.add(p)
+	}
+	rbac.mutex.RUnlock()
+	return set
+}
+
+// add puts `p` into the set unless an equal permission of the same kind is there.
+func (s *EffectiveSet) add(p Permission) {
+	kind := kindOf(p)
+	for _, e := range s.byID[p.ID()] {
+		if kindOf(e) == kind {
+			return
+		}
+	}
+	s.byID[p.ID()] = append(s.byID[p.ID()], p)
+	switch unbind(p).(type) {
+	case *SimplePermission, *DeepPermission:
+	default:
+		s.others = append(s.others, p)
+	}
+}
+
+// kindOf returns the kind and separator of `p`, or its type name.
+func kindOf(p Permission) string {
+	switch v := unbind(p).(type) {
+	case *SimplePermission:
+		return KindSimple
+	case *DeepPermission:
+		return KindDeep + v.Sep
+	case *ResourcePermission:
+		return KindResource + v.Sep
+	case *TemplatePermission:
+		return KindTemplate + v.Sep
+	}
+	return fmt.Sprintf("%T", unbind(p))
+}
+
+// Permissions returns the resolved permissions.
+// Of permissions of different kinds with the same id, the first resolved one is returned.
+func (s *EffectiveSet) Permissions() Permissions {
+	list := make(Permissions, len(s.byID))
+	for id, ps := range s.byID {
+		list[id] = ps[0]
+	}
+	return list
+}
+
+// match returns true if any permission of the set with the id `id` matches `p`.
+func (s *EffectiveSet) match(id string, p Permission) bool {
+	for _, e := range s.byID[id] {
+		if e.Match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Permit returns true if any permission of the set matches `p`.
+// SimplePermission and DeepPermission are looked up by id and by every upper layer of `p`,
+// other kinds are matched one by one.
+func (s *EffectiveSet) Permit(p Permission) bool {
+	if p == nil {
+		return false
+	}
+	if s.catalog != nil {
+		s.catalog.observe(p)
+	}
+	if s.match(p.ID(), p) {
+		return true
+	}
+
+	var id, sep string
+	switch q := unbind(p).(type) {
+	case *DeepPermission:
+		id, sep = q.IDStr, q.Sep
+	case *ResourcePermission:
+		id, sep = q.ID(), q.Sep
+	}
+	if sep != "" {
+		for i := strings.Index(id, sep); i >= 0; {
+			if s.match(id[:i], p) {
+				return true
+			}
+			next := strings.Index(id[i+len(sep):], sep)
+			if next < 0 {
+				break
+			}
+			i += len(sep) + next
+		}
+	}
+
+	for _, e := range s.others {
+		if e.Match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns elements of `items` which `perm` maps to a permission granted
+// to any of `roles`. Permissions of the roles are resolved once for the whole slice,
+// unless a decision logger is set: then every item is checked and logged as AnyGranted does,
+// with the operation Filter.
+func Filter[T any](rbac *RBAC, roles []string, items []T, perm func(T) Permission) []T {
+	result := make([]T, 0, len(items))
+	if rbac.logged() {
+		for _, item := range items {
+			if rbac.anyGranted("Filter", roles, perm(item), nil) {
+				result = append(result, item)
+			}
+		}
+		return result
+	}
+	set := rbac.Effective(roles...)
+	for _, item := range items {
+		p := perm(item)
+		t := checkTimer{m: set.metrics}
+		if t.m != nil {
+			t.start = time.Now()
+		}
+		granted := set.Permit(p)
+		t.done("Filter", p, granted)
+		if granted {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// logged returns true if a decision logger is set.
+func (rbac *RBAC) logged() bool {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	return rbac.logger != nil
+}
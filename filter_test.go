@@ -0,0 +1,112 @@
+package gorbac
+
+import (
+	"strconv"
+	"testing"
+)
+
+type document struct {
+	ID    int
+	Owner string
+}
+
+func TestFilter(t *testing.T) {
+	rbac := prepareTree(t)
+	assert(t, rbac.Add(NewRole("doc-reader").
+		Assign(NewDeepPermission("doc:1:read")).
+		Assign(NewResourcePermission("doc:2", "write")).
+		Assign(MustTemplatePermission("doc:{own}"))))
+
+	docs := []document{{1, "a"}, {2, "b"}, {3, "c"}, {4, "d"}}
+	read := func(d document) Permission {
+		return Bind(NewDeepPermission("doc:"+strconv.Itoa(d.ID)+":read"), map[string]string{"own": "4"})
+	}
+
+	list := Filter(rbac, []string{"doc-reader", "observer"}, docs, read)
+	if len(list) != 3 || list[0].ID != 1 || list[1].ID != 2 || list[2].ID != 4 {
+		t.Fatalf("Unexpected documents: %v", list)
+	}
+	for _, d := range docs {
+		granted := rbac.IsGranted("doc-reader", read(d), nil)
+		if granted != (d.ID != 3) {
+			t.Fatalf("IsGranted differs from Filter on %d", d.ID)
+		}
+	}
+
+	if list := Filter(rbac, []string{"not-exist"}, docs, read); len(list) != 0 {
+		t.Fatalf("Unexpected documents: %v", list)
+	}
+}
+
+func TestRBAC_Effective(t *testing.T) {
+	rbac := prepareTree(t)
+	set := rbac.Effective("reporter")
+
+	if len(set.Permissions()) != 5 {
+		t.Fatalf("5 permissions expected, but %v got", set.Permissions())
+	}
+	for _, id := range []string{"task:create", "task:delete", "user", "system:users:read"} {
+		if !set.Permit(NewDeepPermission(id)) {
+			t.Fatalf("%s should be permitted", id)
+		}
+	}
+	if set.Permit(NewDeepPermission("billing:read")) || set.Permit(NewPermission("tas")) || set.Permit(nil) {
+		t.Fatal("Unexpected permission")
+	}
+}
+
+func TestRBAC_EffectiveKinds(t *testing.T) {
+	rbac := New()
+	assert(t, rbac.Add(NewRole("a").Assign(NewPermission("doc"))))
+	assert(t, rbac.Add(NewRole("b").Assign(NewDeepPermission("doc"))))
+
+	p := NewDeepPermission("doc:1:read")
+	if !AnyGranted(rbac, []string{"a", "b"}, p, nil) {
+		t.Fatal("b should be granted")
+	}
+	if !rbac.Effective("a", "b").Permit(p) {
+		t.Fatal("Permissions with the same id of other kinds should be kept")
+	}
+	if list := Filter(rbac, []string{"a", "b"}, []Permission{p}, func(p Permission) Permission { return p }); len(list) != 1 {
+		t.Fatal("Filter should keep the item")
+	}
+}
+
+func TestFilter_Logged(t *testing.T) {
+	rbac := prepareTree(t)
+	c := prepareCatalog()
+	rbac.SetCatalog(c, false)
+	items := []string{"task:read", "billing:read"}
+	perm := func(id string) Permission { return NewDeepPermission(id) }
+
+	Filter(rbac, []string{"observer"}, items, perm)
+	if n := c.Unknown()["billing:read"]; n != 1 {
+		t.Fatalf("Filter should be observed by the catalog: %d", n)
+	}
+
+	var decisions []Decision
+	rbac.SetDecisionLogger(DecisionLoggerFunc(func(d Decision) { decisions = append(decisions, d) }))
+	list := Filter(rbac, []string{"observer"}, items, perm)
+	if !equalStrings(list, []string{"task:read"}) {
+		t.Fatalf("Unexpected items: %v", list)
+	}
+	if len(decisions) != 2 || decisions[0].Op != "Filter" || !decisions[0].Granted || decisions[1].Granted {
+		t.Fatalf("Unexpected decisions: %+v", decisions)
+	}
+}
+
+func BenchmarkFilter(b *testing.B) {
+	rbac := largeRBAC(b, 1000)
+	items := make([]int, 1000)
+	for i := range items {
+		items[i] = i
+	}
+	perm := func(i int) Permission {
+		return NewDeepPermission("resource-" + strconv.Itoa(i) + ":write")
+	}
+	roles := []string{"role-999"}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		Filter(rbac, roles, items, perm)
+	}
+}
@@ -4,7 +4,7 @@ import "fmt"
 
 // InheritanceCircle returns an error when detecting any circle inheritance.
 func InheritanceCircle(rbac *RBAC) (err error) {
-	rbac.mutex.Lock()
+	rbac.mutex.RLock()
 
 	skipped := make(map[string]struct{}, len(rbac.roles))
 	var stack []string
@@ -14,7 +14,7 @@ func InheritanceCircle(rbac *RBAC) (err error) {
 			break
 		}
 	}
-	rbac.mutex.Unlock()
+	rbac.mutex.RUnlock()
 	return err
 }
 
@@ -49,49 +49,100 @@ func dfs(rbac *RBAC, id string, skipped map[string]struct{}, stack []string) err
 }
 
 // AnyGranted checks if any role has the permission.
-func AnyGranted(rbac *RBAC, roles []string, permission Permission, assert AssertionFunc) (res bool) {
-	rbac.mutex.Lock()
+func AnyGranted(rbac *RBAC, roles []string, permission Permission, assert AssertionFunc) bool {
+	return rbac.anyGranted("AnyGranted", roles, permission, assert)
+}
+
+// anyGranted works like AnyGranted logging the decision as the operation `op`.
+func (rbac *RBAC) anyGranted(op string, roles []string, permission Permission, assert AssertionFunc) (res bool) {
+	rbac.mutex.RLock()
+	rbac.observe(permission)
+	t := rbac.timer()
+	if rbac.logger == nil {
+		for _, role := range roles {
+			if rbac.isGranted(role, permission, assert) {
+				res = true
+				break
+			}
+		}
+		rbac.mutex.RUnlock()
+		t.done(op, permission, res)
+		return res
+	}
+	logger := rbac.logger
+	d := newDecision(op, roles, permission)
 	for _, role := range roles {
-		if rbac.isGranted(role, permission, assert) {
+		if rbac.decide(role, permission, assert, &d) {
 			res = true
 			break
 		}
 	}
-	rbac.mutex.Unlock()
+	rbac.mutex.RUnlock()
+	t.done(op, permission, res)
+	d.finish(res)
+	logger.LogDecision(d)
 	return res
 }
 
 // AllGranted checks if all roles have the permission.
 func AllGranted(rbac *RBAC, roles []string, permission Permission, assert AssertionFunc) (res bool) {
-	rbac.mutex.Lock()
+	rbac.mutex.RLock()
+	rbac.observe(permission)
+	t := rbac.timer()
+	if rbac.logger == nil {
+		for _, role := range roles {
+			if !rbac.isGranted(role, permission, assert) {
+				res = true
+				break
+			}
+		}
+		rbac.mutex.RUnlock()
+		t.done("AllGranted", permission, !res)
+		return !res
+	}
+	logger := rbac.logger
+	d := newDecision("AllGranted", roles, permission)
 	for _, role := range roles {
-		if !rbac.isGranted(role, permission, assert) {
+		if !rbac.decide(role, permission, assert, &d) {
+			d.Role, d.Matched = role, ""
 			res = true
 			break
 		}
 	}
-	rbac.mutex.Unlock()
+	rbac.mutex.RUnlock()
+	t.done("AllGranted", permission, !res)
+	if !res {
+		d.Role, d.Matched = "", ""
+	}
+	d.finish(!res)
+	logger.LogDecision(d)
 	return !res
 }
 
 // WalkHandler is a function defined by user to handle role
 type WalkHandler func(Role, []string) error
 
-// Walk passes each Role to WalkHandler
+// Walk passes each Role to WalkHandler.
+// The read lock is held while `h` is running, so `h` must not change RBAC;
+// use WalkContext for that.
 func Walk(rbac *RBAC, h WalkHandler) (err error) {
 	if h == nil {
 		return
 	}
-	rbac.mutex.Lock()
-	defer rbac.mutex.Unlock()
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
 
+	ids := make([]string, 0, len(rbac.roles))
 	for id := range rbac.roles {
+		ids = append(ids, id)
+	}
+	for _, id := range rbac.sortIDs(ids) {
 		var parents []string
 		r := rbac.roles[id]
 		for parent := range rbac.parents[id] {
 			parents = append(parents, parent)
 		}
-		if err := h(r, parents); err != nil {
+		if err := h(r, rbac.sortIDs(parents)); err != nil {
 			return err
 		}
 	}
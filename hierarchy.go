@@ -0,0 +1,141 @@
+package gorbac
+
+// Children returns roles which have the role `id` as a direct parent.
+// If the role is not existing, an error will be returned.
+func (rbac *RBAC) Children(id string) ([]string, error) {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	if _, ok := rbac.roles[id]; !ok {
+		return nil, roleNotExist("Children", id)
+	}
+	var children []string
+	for child := range rbac.children[id] {
+		children = append(children, child)
+	}
+	return rbac.sortIDs(children), nil
+}
+
+// Ancestors returns all roles the role `id` inherits from, directly or transitively.
+// If the role is not existing, an error will be returned.
+func (rbac *RBAC) Ancestors(id string) ([]string, error) {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	if _, ok := rbac.roles[id]; !ok {
+		return nil, roleNotExist("Ancestors", id)
+	}
+	return rbac.sortIDs(rbac.reach(id, rbac.parents)), nil
+}
+
+// Descendants returns all roles inheriting from the role `id`, directly or transitively,
+// i.e. the roles which gain every permission assigned to `id`.
+// If the role is not existing, an error will be returned.
+func (rbac *RBAC) Descendants(id string) ([]string, error) {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	if _, ok := rbac.roles[id]; !ok {
+		return nil, roleNotExist("Descendants", id)
+	}
+	return rbac.sortIDs(rbac.reach(id, rbac.children)), nil
+}
+
+// reach returns the roles reachable from `id` by `edges`, excluding `id` itself.
+func (rbac *RBAC) reach(id string, edges map[string]map[string]struct{}) []string {
+	var result []string
+	visited := map[string]struct{}{id: empty}
+	stack := []string{id}
+	for len(stack) > 0 {
+		cur := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for next := range edges[cur] {
+			if _, ok := visited[next]; ok {
+				continue
+			}
+			visited[next] = empty
+			if _, ok := rbac.roles[next]; !ok {
+				continue
+			}
+			result = append(result, next)
+			stack = append(stack, next)
+		}
+	}
+	return result
+}
+
+// Depth returns the length of the longest chain of parents of the role `id`.
+// Roles without parents have the depth 0.
+// If the role is not existing, or its ancestors have a circle inheritance,
+// an error will be returned.
+func (rbac *RBAC) Depth(id string) (int, error) {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	if _, ok := rbac.roles[id]; !ok {
+		return 0, roleNotExist("Depth", id)
+	}
+	return rbac.depth(id, make(map[string]int), make(map[string]struct{}))
+}
+
+func (rbac *RBAC) depth(id string, known map[string]int, stack map[string]struct{}) (int, error) {
+	if d, ok := known[id]; ok {
+		return d, nil
+	}
+	if _, ok := stack[id]; ok {
+		return 0, ErrFoundCircle
+	}
+	stack[id] = empty
+	result := 0
+	for parent := range rbac.parents[id] {
+		if _, ok := rbac.roles[parent]; !ok {
+			continue
+		}
+		d, err := rbac.depth(parent, known, stack)
+		if err != nil {
+			return 0, err
+		}
+		if d+1 > result {
+			result = d + 1
+		}
+	}
+	delete(stack, id)
+	known[id] = result
+	return result, nil
+}
+
+// Roots returns roles without parents.
+func (rbac *RBAC) Roots() []string {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	var roots []string
+	for id := range rbac.roles {
+		if len(rbac.parents[id]) == 0 {
+			roots = append(roots, id)
+		}
+	}
+	return rbac.sortIDs(roots)
+}
+
+// Leaves returns roles without children.
+func (rbac *RBAC) Leaves() []string {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	var leaves []string
+	for id := range rbac.roles {
+		if len(rbac.children[id]) == 0 {
+			leaves = append(leaves, id)
+		}
+	}
+	return rbac.sortIDs(leaves)
+}
+
+// RolesWithPermission returns roles which permit `p` by their own permissions,
+// not taking inheritance into account.
+func (rbac *RBAC) RolesWithPermission(p Permission) []string {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	var result []string
+	for id, role := range rbac.roles {
+		if role.Permit(p) {
+			result = append(result, id)
+		}
+	}
+	return rbac.sortIDs(result)
+}
@@ -0,0 +1,117 @@
+package gorbac
+
+import (
+	"errors"
+	"testing"
+)
+
+// prepareTree builds the hierarchy:
+//
+//	root <- admin <- moderator <- observer
+//	                           <- reporter
+func prepareTree(t *testing.T) *RBAC {
+	rbac := New(WithSortedOutput())
+	for id, perms := range map[string][]string{
+		"observer":  {"task:read"},
+		"reporter":  {"task:create", "task:read"},
+		"moderator": {"task"},
+		"admin":     {"user"},
+		"root":      {"system"},
+	} {
+		role := NewRole(id)
+		for _, p := range perms {
+			role.Assign(NewDeepPermission(p))
+		}
+		assert(t, rbac.Add(role))
+	}
+	assert(t, rbac.SetParent("observer", "moderator"))
+	assert(t, rbac.SetParent("reporter", "moderator"))
+	assert(t, rbac.SetParent("moderator", "admin"))
+	assert(t, rbac.SetParent("admin", "root"))
+	return rbac
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestRBAC_Hierarchy(t *testing.T) {
+	rbac := prepareTree(t)
+
+	if list, err := rbac.Children("moderator"); err != nil {
+		t.Fatal(err)
+	} else if !equalStrings(list, []string{"observer", "reporter"}) {
+		t.Fatalf("Unexpected children: %v", list)
+	}
+	if list, err := rbac.Ancestors("observer"); err != nil {
+		t.Fatal(err)
+	} else if !equalStrings(list, []string{"admin", "moderator", "root"}) {
+		t.Fatalf("Unexpected ancestors: %v", list)
+	}
+	if list, err := rbac.Descendants("admin"); err != nil {
+		t.Fatal(err)
+	} else if !equalStrings(list, []string{"moderator", "observer", "reporter"}) {
+		t.Fatalf("Unexpected descendants: %v", list)
+	}
+	if d, err := rbac.Depth("reporter"); err != nil {
+		t.Fatal(err)
+	} else if d != 3 {
+		t.Fatalf("Depth 3 expected, but %d got", d)
+	}
+	if roots := rbac.Roots(); !equalStrings(roots, []string{"root"}) {
+		t.Fatalf("Unexpected roots: %v", roots)
+	}
+	if leaves := rbac.Leaves(); !equalStrings(leaves, []string{"observer", "reporter"}) {
+		t.Fatalf("Unexpected leaves: %v", leaves)
+	}
+	if list := rbac.RolesWithPermission(NewDeepPermission("task:read")); !equalStrings(list, []string{"moderator", "observer", "reporter"}) {
+		t.Fatalf("Unexpected roles: %v", list)
+	}
+
+	for _, f := range []func(string) ([]string, error){rbac.Children, rbac.Ancestors, rbac.Descendants} {
+		if _, err := f("not-exist"); !errors.Is(err, ErrRoleNotExist) {
+			t.Fatalf("%s needed", ErrRoleNotExist)
+		}
+	}
+	if _, err := rbac.Depth("not-exist"); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatalf("%s needed", ErrRoleNotExist)
+	}
+
+	assert(t, rbac.SetParent("root", "observer"))
+	if _, err := rbac.Depth("admin"); err != ErrFoundCircle {
+		t.Fatalf("%s needed", ErrFoundCircle)
+	}
+	if list, err := rbac.Ancestors("admin"); err != nil {
+		t.Fatal(err)
+	} else if len(list) != 3 {
+		t.Fatalf("Circle should be walked once: %v", list)
+	}
+}
+
+func TestRBAC_ChildrenIndex(t *testing.T) {
+	rbac := prepareTree(t)
+
+	assert(t, rbac.Remove("moderator"))
+	if _, ok := rbac.children["moderator"]; ok {
+		t.Fatal("Children of the removed role should be dropped")
+	}
+	if _, ok := rbac.children["admin"]["moderator"]; ok {
+		t.Fatal("Removed role should not be a child")
+	}
+	if _, ok := rbac.parents["observer"]["moderator"]; ok {
+		t.Fatal("Removed role should not be a parent")
+	}
+
+	assert(t, rbac.RemoveParent("admin", "root"))
+	if list, _ := rbac.Children("root"); len(list) != 0 {
+		t.Fatalf("Unexpected children: %v", list)
+	}
+}
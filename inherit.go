@@ -0,0 +1,403 @@
+package gorbac
+
+import (
+	"errors"
+	"sort"
+)
+
+// DefaultMaxDepth is the maximum number of parent edges followed by a check
+// unless WithMaxDepth sets another one. It protects against stack exhaustion
+// caused by deep or circle inheritance.
+const DefaultMaxDepth = 1024
+
+// WithMaxDepth limits the number of parent edges followed by a check to `n`,
+// permissions of farther ancestors are not inherited.
+func WithMaxDepth(n int) Option {
+	return func(rbac *RBAC) {
+		rbac.maxDepth = n
+	}
+}
+
+// EdgeOption configures a parent edge bound by SetParent or SetParents.
+type EdgeOption func(*edge)
+
+// InheritDepth limits the inheritance through the edge to roles at most `n` edges
+// away from the child, e.g. 1 passes permissions assigned to the parent itself only.
+// 0 means no limit.
+func InheritDepth(n int) EdgeOption {
+	return func(e *edge) {
+		e.depth = n
+	}
+}
+
+// InheritOnly makes the edge pass only permissions matched by any of `filter`,
+// e.g. InheritOnly(NewDeepPermission("report")) passes `report:read`, but not `task:read`.
+// No filter passes every permission.
+func InheritOnly(filter ...Permission) EdgeOption {
+	return func(e *edge) {
+		e.filter = append([]Permission(nil), filter...)
+	}
+}
+
+type edgeKey struct {
+	id, parent string
+}
+
+// edge holds options of a parent edge.
+type edge struct {
+	depth  int
+	filter []Permission
+}
+
+func (e edge) isZero() bool {
+	return e.depth <= 0 && len(e.filter) == 0
+}
+
+// permits returns true if the edge passes `p`.
+func (e edge) permits(p Permission) bool {
+	if len(e.filter) == 0 {
+		return true
+	}
+	for _, f := range e.filter {
+		if f.Match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// narrow returns the part of the inherited permission `p` passed by the edge:
+// `p` itself if a filter matches it, or the filters which `p` matches.
+// Permissions of other kinds than SimplePermission and DeepPermission,
+// which can't be compared with filters in advance, e.g. templates,
+// are returned as NarrowedPermission.
+func (e edge) narrow(p Permission) []Permission {
+	if e.permits(p) {
+		return []Permission{p}
+	}
+	var result []Permission
+	for _, f := range e.filter {
+		if p.Match(f) {
+			result = append(result, f)
+		}
+	}
+	if len(result) > 0 {
+		return result
+	}
+	switch unbind(p).(type) {
+	case *SimplePermission, *DeepPermission:
+		return nil
+	}
+	return []Permission{&NarrowedPermission{Permission: p, Filter: e.filter}}
+}
+
+// NarrowedPermission is an inherited permission passed by an edge filter
+// which can't be narrowed in advance, e.g. a template.
+// It matches what both the wrapped permission and any of the filters match.
+type NarrowedPermission struct {
+	Permission
+	Filter []Permission
+}
+
+// Match another permission.
+func (p *NarrowedPermission) Match(a Permission) bool {
+	if a == nil || !p.Permission.Match(a) {
+		return false
+	}
+	return edge{filter: p.Filter}.permits(a)
+}
+
+// ErrEdgesIncompatible occurred if two edges can't be replaced by a single one
+// passing the same permissions
+var ErrEdgesIncompatible = errors.New("edge options can't be combined")
+
+// chain returns the edge replacing the path over the edge `e`,
+// a role limited to `depth` and the edge `next`,
+// and false if nothing passes the path.
+// If filters of both edges can't be intersected, ErrEdgesIncompatible is returned.
+func chain(e edge, depth int, next edge) (edge, bool, error) {
+	if e.depth == 1 {
+		return edge{}, false, nil
+	}
+	var result edge
+	for _, n := range []int{e.depth - 1, depth, next.depth} {
+		if n > 0 && (result.depth == 0 || n < result.depth) {
+			result.depth = n
+		}
+	}
+	switch {
+	case len(e.filter) == 0:
+		result.filter = next.filter
+	case len(next.filter) == 0:
+		result.filter = e.filter
+	default:
+		for _, f := range e.filter {
+			for _, g := range next.filter {
+				p, ok := intersect(f, g)
+				if !ok {
+					return result, false, ErrEdgesIncompatible
+				}
+				if p != nil {
+					result.filter = append(result.filter, p)
+				}
+			}
+		}
+		if len(result.filter) == 0 {
+			return result, false, nil
+		}
+	}
+	return result, true, nil
+}
+
+// intersect returns the filter passing what both `f` and `g` pass, nil if nothing,
+// and false if it can't be expressed by one of them.
+func intersect(f, g Permission) (Permission, bool) {
+	switch a := unbind(f).(type) {
+	case *SimplePermission:
+		if b, ok := unbind(g).(*SimplePermission); ok {
+			if a.IDStr == b.IDStr {
+				return f, true
+			}
+			return nil, true
+		}
+	case *DeepPermission:
+		if b, ok := unbind(g).(*DeepPermission); ok && a.Sep == b.Sep {
+			switch {
+			case a.Match(b):
+				return g, true
+			case b.Match(a):
+				return f, true
+			}
+			return nil, true
+		}
+	}
+	return nil, false
+}
+
+// setEdge binds `parent` to the role `id` with options `opts`,
+// replacing options of an existing edge. It returns the resulting options.
+// The lock has to be held by the caller.
+func (rbac *RBAC) setEdge(id, parent string, opts []EdgeOption) edge {
+	var e edge
+	for _, opt := range opts {
+		opt(&e)
+	}
+	rbac.bind(id, parent)
+	if e.isZero() {
+		delete(rbac.edges, edgeKey{id, parent})
+	} else {
+		rbac.edges[edgeKey{id, parent}] = e
+	}
+	return e
+}
+
+// options returns options reproducing the edge.
+func (e edge) options() []EdgeOption {
+	return []EdgeOption{InheritDepth(e.depth), InheritOnly(e.filter...)}
+}
+
+// edgeEvent returns the EventSetParent describing the edge `e`.
+func edgeEvent(id, parent string, e edge) Event {
+	return Event{Op: EventSetParent, Role: id, Parent: parent, Depth: e.depth, Permissions: permissionRecords(e.filter)}
+}
+
+// edgeOptions returns options reproducing the edge between the role `id` and its `parent`.
+// The lock has to be held by the caller.
+func (rbac *RBAC) edgeOptions(id, parent string) []EdgeOption {
+	e, ok := rbac.edges[edgeKey{id, parent}]
+	if !ok {
+		return nil
+	}
+	return e.options()
+}
+
+// SetInheritDepth limits the inheritance of the role `id` to ancestors at most `n` edges away,
+// the limit applies to permissions the role passes to its children as well.
+// 0 removes the limit. If the role is not existing, an error will be returned.
+func (rbac *RBAC) SetInheritDepth(id string, n int) error {
+	rbac.mutex.Lock()
+	if _, ok := rbac.roles[id]; !ok {
+		rbac.mutex.Unlock()
+		return roleNotExist("SetInheritDepth", id)
+	}
+	if n > 0 {
+		rbac.depths[id] = n
+	} else {
+		delete(rbac.depths, id)
+		n = 0
+	}
+	rbac.emit(Event{Op: EventSetDepth, Role: id, Depth: n})
+	rbac.mutex.Unlock()
+	rbac.notify()
+	return nil
+}
+
+// RoleInheritDepth returns the inheritance limit of the role `id`, 0 if there is none.
+// If the role is not existing, an error will be returned.
+func (rbac *RBAC) RoleInheritDepth(id string) (int, error) {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	if _, ok := rbac.roles[id]; !ok {
+		return 0, roleNotExist("RoleInheritDepth", id)
+	}
+	return rbac.depths[id], nil
+}
+
+// filtered returns true if any edge has a filter.
+// The lock has to be held by the caller.
+func (rbac *RBAC) filtered() bool {
+	for _, e := range rbac.edges {
+		if len(e.filter) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// limited returns true if any inheritance limit, edge filter or a custom maximum depth is set,
+// or the hierarchy has more roles than edges a check may follow,
+// so the default maximum depth may cut it.
+// The lock has to be held by the caller.
+func (rbac *RBAC) limited() bool {
+	return len(rbac.edges) > 0 || len(rbac.depths) > 0 || rbac.maxDepth > 0 || len(rbac.roles) > rbac.depthLimit()
+}
+
+// depthLimit returns the number of edges a check may follow from a role.
+func (rbac *RBAC) depthLimit() int {
+	if rbac.maxDepth > 0 {
+		return rbac.maxDepth
+	}
+	return DefaultMaxDepth
+}
+
+// enter returns the number of edges which may be followed from the role `id`,
+// if `remaining` edges may be followed by the path reaching it.
+// The lock has to be held by the caller.
+func (rbac *RBAC) enter(id string, remaining int) int {
+	if len(rbac.depths) == 0 {
+		return remaining
+	}
+	if n, ok := rbac.depths[id]; ok && n < remaining {
+		return n
+	}
+	return remaining
+}
+
+// cross returns the number of edges which may be followed from the `parent` of the role `id`,
+// if `remaining` edges may be followed from the role.
+// The lock has to be held by the caller.
+func (rbac *RBAC) cross(id, parent string, remaining int) int {
+	if len(rbac.edges) == 0 {
+		return remaining - 1
+	}
+	if e, ok := rbac.edges[edgeKey{id, parent}]; ok && e.depth > 0 && e.depth < remaining {
+		remaining = e.depth
+	}
+	return remaining - 1
+}
+
+// inherited returns the role `id` followed by the roles it inherits permissions from,
+// nearest first, honoring inheritance limits, with the number of edges to each of them.
+// Edge filters are not taken into account.
+// The lock has to be held by the caller.
+func (rbac *RBAC) inherited(id string) ([]string, map[string]int) {
+	if _, ok := rbac.roles[id]; !ok {
+		return nil, nil
+	}
+	remaining := map[string]int{id: rbac.enter(id, rbac.depthLimit())}
+	distance := map[string]int{id: 0}
+	order := []string{id}
+	queue := []string{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if remaining[current] <= 0 {
+			continue
+		}
+		parents := make([]string, 0, len(rbac.parents[current]))
+		for parent := range rbac.parents[current] {
+			if _, ok := rbac.roles[parent]; ok {
+				parents = append(parents, parent)
+			}
+		}
+		sort.Strings(parents)
+		for _, parent := range parents {
+			r := rbac.enter(parent, rbac.cross(current, parent, remaining[current]))
+			prev, seen := remaining[parent]
+			if !seen {
+				order = append(order, parent)
+				distance[parent] = distance[current] + 1
+			}
+			if !seen || r > prev {
+				remaining[parent] = r
+				queue = append(queue, parent)
+			}
+		}
+	}
+	return order, distance
+}
+
+// origin is a permission visible to a role with the role it is assigned to.
+type origin struct {
+	p    Permission
+	from string
+	dist int
+}
+
+// nearer returns true if `o` is preferred to `cur` for the same permission id:
+// a permission not narrowed by a filter first, then the nearest one, then the least role id.
+func (o origin) nearer(cur origin) bool {
+	_, narrowed := o.p.(*NarrowedPermission)
+	_, curNarrowed := cur.p.(*NarrowedPermission)
+	if narrowed != curNarrowed {
+		return curNarrowed
+	}
+	return o.dist < cur.dist || o.dist == cur.dist && o.from < cur.from
+}
+
+type visitKey struct {
+	id        string
+	remaining int
+}
+
+// visible returns permissions of the role `id`, including inherited ones
+// narrowed by edge filters within inheritance limits, keyed by id.
+// The nearest origin of a permission is kept, the least role id on a tie,
+// preferring one not narrowed by a filter.
+// The lock has to be held by the caller.
+func (rbac *RBAC) visible(id string) map[string]origin {
+	if _, ok := rbac.roles[id]; !ok {
+		return nil
+	}
+	return rbac.collect(id, rbac.depthLimit(), make(map[visitKey]map[string]origin))
+}
+
+func (rbac *RBAC) collect(id string, remaining int, memo map[visitKey]map[string]origin) map[string]origin {
+	key := visitKey{id, remaining}
+	if result, ok := memo[key]; ok {
+		return result
+	}
+	result := make(map[string]origin)
+	for _, p := range rbac.roles[id].Permissions() {
+		result[p.ID()] = origin{p, id, 0}
+	}
+	remaining = rbac.enter(id, remaining)
+	if remaining > 0 {
+		for parent := range rbac.parents[id] {
+			if _, ok := rbac.roles[parent]; !ok {
+				continue
+			}
+			e := rbac.edges[edgeKey{id, parent}]
+			for _, o := range rbac.collect(parent, rbac.cross(id, parent, remaining), memo) {
+				for _, p := range e.narrow(o.p) {
+					next := origin{p, o.from, o.dist + 1}
+					if cur, ok := result[p.ID()]; !ok || next.nearer(cur) {
+						result[p.ID()] = next
+					}
+				}
+			}
+		}
+	}
+	memo[key] = result
+	return result
+}
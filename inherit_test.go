@@ -0,0 +1,342 @@
+package gorbac
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRBAC_EdgeInheritDepth(t *testing.T) {
+	rbac := prepareTree(t)
+	assert(t, rbac.SetParent("observer", "moderator", InheritDepth(1)))
+
+	if !rbac.IsGranted("observer", NewDeepPermission("task:delete"), nil) {
+		t.Fatal("observer should inherit from moderator")
+	}
+	if rbac.IsGranted("observer", NewDeepPermission("user"), nil) {
+		t.Fatal("observer should not inherit from admin")
+	}
+	if !rbac.IsGranted("reporter", NewDeepPermission("system"), nil) {
+		t.Fatal("reporter should not be limited")
+	}
+	if ids := permissionIDs(rbac.Permissions("observer")); !equalStrings(ids, []string{"task", "task:read"}) {
+		t.Fatalf("Unexpected permissions: %v", ids)
+	}
+	if list := rbac.RolesGranted(NewDeepPermission("user"), nil); !equalStrings(list, []string{"admin", "moderator", "reporter"}) {
+		t.Fatalf("Unexpected roles: %v", list)
+	}
+	if res := rbac.CheckMany([]string{"observer"}, []Permission{NewDeepPermission("task"), NewDeepPermission("system")}, nil); !res[0] || res[1] {
+		t.Fatalf("Unexpected results: %v", res)
+	}
+	if rbac.Effective("observer").Permit(NewDeepPermission("user")) {
+		t.Fatal("Effective set should honor limits")
+	}
+
+	assert(t, rbac.SetParent("observer", "moderator", InheritDepth(2)))
+	if !rbac.IsGranted("observer", NewDeepPermission("user"), nil) || rbac.IsGranted("observer", NewDeepPermission("system"), nil) {
+		t.Fatal("observer should inherit from admin only")
+	}
+	assert(t, rbac.SetParent("observer", "moderator"))
+	if !rbac.IsGranted("observer", NewDeepPermission("system"), nil) {
+		t.Fatal("Binding again should remove the limit")
+	}
+}
+
+func TestRBAC_RoleInheritDepth(t *testing.T) {
+	rbac := prepareTree(t)
+	assert(t, rbac.SetInheritDepth("moderator", 1))
+
+	if depth, err := rbac.RoleInheritDepth("moderator"); err != nil || depth != 1 {
+		t.Fatalf("Unexpected depth: %d, %v", depth, err)
+	}
+	for _, id := range []string{"moderator", "observer"} {
+		if !rbac.IsGranted(id, NewDeepPermission("user"), nil) || rbac.IsGranted(id, NewDeepPermission("system"), nil) {
+			t.Fatalf("%s should inherit from admin only", id)
+		}
+	}
+	scoped, err := rbac.PermissionsUnder("observer", NewDeepPermission("system"))
+	assert(t, err)
+	if len(scoped) != 0 {
+		t.Fatalf("Unexpected permissions: %v", scoped)
+	}
+
+	assert(t, rbac.SetInheritDepth("moderator", 0))
+	if !rbac.IsGranted("observer", NewDeepPermission("system"), nil) {
+		t.Fatal("Limit should be removed")
+	}
+	if err := rbac.SetInheritDepth("not-exist", 1); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatalf("%s needed", ErrRoleNotExist)
+	}
+}
+
+func TestRBAC_MaxDepth(t *testing.T) {
+	rbac := New(WithMaxDepth(2))
+	for _, id := range []string{"a", "b", "c", "d"} {
+		assert(t, rbac.Add(NewRole(id).Assign(NewPermission(id))))
+	}
+	assert(t, rbac.SetParent("a", "b"))
+	assert(t, rbac.SetParent("b", "c"))
+	assert(t, rbac.SetParent("c", "d"))
+
+	if !rbac.IsGranted("a", NewPermission("c"), nil) || rbac.IsGranted("a", NewPermission("d"), nil) {
+		t.Fatal("Permissions farther than the maximum depth should not be inherited")
+	}
+	if ids := permissionIDs(rbac.Permissions("a")); !equalStrings(ids, []string{"a", "b", "c"}) {
+		t.Fatalf("Unexpected permissions: %v", ids)
+	}
+
+	// a circle doesn't exhaust the stack
+	rbac = New()
+	assert(t, rbac.Add(NewRole("x")))
+	assert(t, rbac.Add(NewRole("y")))
+	assert(t, rbac.SetParent("x", "y"))
+	assert(t, rbac.SetParent("y", "x"))
+	if rbac.IsGranted("x", NewPermission("none"), nil) || len(rbac.Permissions("x")) != 0 {
+		t.Fatal("Nothing should be granted")
+	}
+
+	// listings agree with checks cut by the default maximum depth
+	rbac = New()
+	n := DefaultMaxDepth + 76
+	for i := 0; i <= n; i++ {
+		assert(t, rbac.Add(NewRole(fmt.Sprint(i))))
+		if i > 0 {
+			assert(t, rbac.SetParent(fmt.Sprint(i), fmt.Sprint(i-1)))
+		}
+	}
+	assert(t, rbac.Assign("0", NewPermission("root")))
+	list := rbac.RolesGranted(NewPermission("root"), nil)
+	if len(list) != DefaultMaxDepth+1 || rbac.IsGranted(fmt.Sprint(n), NewPermission("root"), nil) {
+		t.Fatalf("RolesGranted differs from IsGranted: %d roles", len(list))
+	}
+}
+
+func TestRBAC_InheritDepthSerialization(t *testing.T) {
+	rbac := prepareTree(t)
+	var events []Event
+	rbac.OnChange(func(e Event) {
+		events = append(events, e)
+	})
+	assert(t, rbac.SetParent("observer", "moderator", InheritDepth(1)))
+	assert(t, rbac.SetInheritDepth("reporter", 2))
+
+	replica := prepareTree(t)
+	assert(t, replica.Apply(events...))
+	expected, err := rbac.Export()
+	assert(t, err)
+	got, err := replica.Export()
+	assert(t, err)
+	if !equalPolicies(expected, got) {
+		t.Fatalf("Replayed policy differs:\n%+v\n%+v", expected, got)
+	}
+
+	cp, err := NewFromPolicy(expected)
+	assert(t, err)
+	if cp.IsGranted("observer", NewDeepPermission("user"), nil) || cp.IsGranted("reporter", NewDeepPermission("system"), nil) {
+		t.Fatal("Limits should survive the policy")
+	}
+
+	var buf bytes.Buffer
+	assert(t, WriteSnapshot(&buf, rbac))
+	cp, err = ReadSnapshot(&buf)
+	assert(t, err)
+	got, err = cp.Export()
+	assert(t, err)
+	if !equalPolicies(expected, got) {
+		t.Fatalf("Snapshot policy differs:\n%+v\n%+v", expected, got)
+	}
+
+	assert(t, rbac.Rename("observer", "viewer"))
+	if rbac.IsGranted("viewer", NewDeepPermission("user"), nil) {
+		t.Fatal("Renamed role should keep limits")
+	}
+
+	buf.Reset()
+	report, err := ExportCasbin(rbac, &buf)
+	assert(t, err)
+	if strings.Contains(buf.String(), "g,viewer") || strings.Contains(buf.String(), "g,reporter") || len(report.Skipped) != 2 {
+		t.Fatalf("Limited edges should be skipped: %s %+v", buf.String(), report)
+	}
+}
+
+func prepareAuditor(t *testing.T) *RBAC {
+	rbac := New()
+	assert(t, rbac.Add(NewRole("sales").Assign(NewDeepPermission("report:sales")).Assign(NewDeepPermission("task:write"))))
+	assert(t, rbac.Add(NewRole("hr").Assign(NewDeepPermission("report:hr")).Assign(NewDeepPermission("user:write"))))
+	assert(t, rbac.Add(NewRole("finance").Assign(NewDeepPermission("report")).Assign(NewDeepPermission("invoice:write"))))
+	assert(t, rbac.Add(NewRole("auditor")))
+	assert(t, rbac.Add(NewRole("intern")))
+	for _, parent := range []string{"sales", "hr", "finance"} {
+		assert(t, rbac.SetParent("auditor", parent, InheritOnly(NewDeepPermission("report"))))
+	}
+	assert(t, rbac.SetParent("intern", "auditor", InheritOnly(NewDeepPermission("report:sales"))))
+	return rbac
+}
+
+func TestRBAC_EdgeFilter(t *testing.T) {
+	rbac := prepareAuditor(t)
+
+	for _, id := range []string{"report:sales:q2", "report:hr", "report:finance"} {
+		if !rbac.IsGranted("auditor", NewDeepPermission(id), nil) {
+			t.Fatalf("auditor should be granted %s", id)
+		}
+	}
+	for _, id := range []string{"task:write", "user:write", "invoice:write"} {
+		if rbac.IsGranted("auditor", NewDeepPermission(id), nil) {
+			t.Fatalf("auditor should not be granted %s", id)
+		}
+	}
+	if !rbac.IsGranted("intern", NewDeepPermission("report:sales"), nil) || rbac.IsGranted("intern", NewDeepPermission("report:hr"), nil) {
+		t.Fatal("Filters on the path should all apply")
+	}
+
+	if ids := permissionIDs(rbac.Permissions("auditor")); !equalStrings(ids, []string{"report", "report:hr", "report:sales"}) {
+		t.Fatalf("Unexpected permissions: %v", ids)
+	}
+	if ids := permissionIDs(rbac.Permissions("intern")); !equalStrings(ids, []string{"report:sales"}) {
+		t.Fatalf("Broader permissions should be narrowed: %v", ids)
+	}
+	scoped, err := rbac.PermissionsUnder("intern", NewDeepPermission("report"))
+	assert(t, err)
+	if len(scoped) != 1 || !scoped[0].Inherited || scoped[0].From != "finance" {
+		t.Fatalf("Unexpected scoped permissions: %+v", scoped)
+	}
+	if list := rbac.RolesGranted(NewDeepPermission("report:hr"), nil); !equalStrings(list, []string{"auditor", "finance", "hr"}) {
+		t.Fatalf("Unexpected roles: %v", list)
+	}
+	if res := rbac.CheckMany([]string{"intern"}, []Permission{NewDeepPermission("report:sales"), NewDeepPermission("task:write")}, nil); !res[0] || res[1] {
+		t.Fatalf("Unexpected results: %v", res)
+	}
+	if set := rbac.Effective("intern"); !set.Permit(NewDeepPermission("report:sales:q2")) || set.Permit(NewDeepPermission("report:hr")) {
+		t.Fatal("Effective set should honor filters")
+	}
+	if rbac.IsGranted("auditor", nil, nil) {
+		t.Fatal("nil permission should not be granted")
+	}
+
+	assert(t, rbac.SetParent("auditor", "sales"))
+	if !rbac.IsGranted("auditor", NewDeepPermission("task:write"), nil) {
+		t.Fatal("Binding again should remove the filter")
+	}
+}
+
+func TestRBAC_EdgeFilterSerialization(t *testing.T) {
+	rbac := New()
+	var events []Event
+	rbac.OnChange(func(e Event) {
+		events = append(events, e)
+	})
+	source := prepareAuditor(t)
+	policy, err := source.Export()
+	assert(t, err)
+	assert(t, rbac.Import(policy))
+	if !equalStrings(permissionIDs(rbac.Permissions("intern")), []string{"report:sales"}) {
+		t.Fatal("Filters should survive the policy")
+	}
+
+	replica := New()
+	assert(t, replica.Apply(events...))
+	got, err := replica.Export()
+	assert(t, err)
+	if !equalPolicies(policy, got) {
+		t.Fatalf("Replayed policy differs:\n%+v\n%+v", policy, got)
+	}
+
+	var buf bytes.Buffer
+	assert(t, WriteSnapshot(&buf, rbac))
+	cp, err := ReadSnapshot(&buf)
+	assert(t, err)
+	if got, err = cp.Export(); err != nil || !equalPolicies(policy, got) {
+		t.Fatalf("Snapshot policy differs:\n%+v\n%+v", policy, got)
+	}
+
+	ctx := context.Background()
+	s := openSQLStorage(t)
+	assert(t, SaveRBAC(ctx, s, rbac))
+	if got, err = s.Load(ctx); err != nil || !equalPolicies(policy, got) {
+		t.Fatalf("Stored policy differs:\n%+v\n%+v", policy, got)
+	}
+	applied := openSQLStorage(t)
+	assert(t, applied.Apply(ctx, events))
+	if got, err = applied.Load(ctx); err != nil || !equalPolicies(policy, got) {
+		t.Fatalf("Applied policy differs:\n%+v\n%+v", policy, got)
+	}
+
+	buf.Reset()
+	report, err := ExportCasbin(rbac, &buf)
+	assert(t, err)
+	if strings.Contains(buf.String(), "g,") || len(report.Skipped) != 4 {
+		t.Fatalf("Filtered edges should be skipped: %s %+v", buf.String(), report)
+	}
+}
+
+func TestRBAC_CheckCycles(t *testing.T) {
+	rbac := New()
+	for _, id := range []string{"a", "b", "c"} {
+		assert(t, rbac.Add(NewRole(id)))
+	}
+	assert(t, rbac.SetParents("a", []string{"b", "c"}))
+	assert(t, rbac.SetParent("b", "a"))
+	assert(t, rbac.SetParent("c", "a"))
+
+	done := make(chan bool)
+	go func() {
+		done <- rbac.IsGranted("a", NewPermission("x"), nil)
+	}()
+	select {
+	case granted := <-done:
+		if granted {
+			t.Fatal("Nothing should be granted")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Check should not hang on cycles")
+	}
+
+	assert(t, rbac.Assign("c", NewPermission("x")))
+	if !rbac.IsGranted("b", NewPermission("x"), nil) {
+		t.Fatal("Permission should be found through the cycle")
+	}
+}
+
+func TestRBAC_EdgeFilterTemplates(t *testing.T) {
+	rbac := New()
+	parent := NewRole("member")
+	parent.Assign(MustTemplatePermission("user:{subject}:profile"))
+	parent.Assign(NewDeepPermission("task"))
+	assert(t, rbac.Add(parent))
+	assert(t, rbac.Add(NewRole("guest")))
+	assert(t, rbac.SetParent("guest", "member", InheritOnly(NewDeepPermission("user"))))
+
+	vars := map[string]string{"subject": "42"}
+	items := []Permission{
+		Bind(NewDeepPermission("user:42:profile"), vars),
+		Bind(NewDeepPermission("user:42:profile:edit"), vars),
+		Bind(NewDeepPermission("user:7:profile"), vars),
+		NewDeepPermission("task:read"),
+	}
+	var want []Permission
+	for _, p := range items {
+		if rbac.IsGranted("guest", p, nil) {
+			want = append(want, p)
+		}
+	}
+	if len(want) != 2 {
+		t.Fatalf("Unexpected granted permissions: %v", want)
+	}
+	got := Filter(rbac, []string{"guest"}, items, func(p Permission) Permission { return p })
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Fatalf("Filter differs from IsGranted: %v, %v", got, want)
+	}
+	if _, ok := rbac.Permissions("guest")["user:{subject}:profile"]; !ok {
+		t.Fatal("Template should be inherited through the filter")
+	}
+
+	assert(t, rbac.SetParent("guest", "member", InheritOnly(NewDeepPermission("user:7"))))
+	if rbac.Effective("guest").Permit(items[0]) || rbac.IsGranted("guest", items[0], nil) {
+		t.Fatal("Template should be narrowed by the filter")
+	}
+}
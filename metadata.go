@@ -0,0 +1,226 @@
+package gorbac
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidSelector occurred if a label selector can't be parsed
+var ErrInvalidSelector = errors.New("invalid selector")
+
+// Metadata describes a role or a permission for humans.
+// It doesn't take part in authorization.
+type Metadata struct {
+	Title       string            `json:"title,omitempty"`
+	Description string            `json:"description,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+}
+
+// IsZero returns true if nothing is set.
+func (m Metadata) IsZero() bool {
+	return m.Title == "" && m.Description == "" && len(m.Labels) == 0
+}
+
+func (m Metadata) clone() Metadata {
+	if m.Labels == nil {
+		return m
+	}
+	labels := make(map[string]string, len(m.Labels))
+	for k, v := range m.Labels {
+		labels[k] = v
+	}
+	m.Labels = labels
+	return m
+}
+
+// sortedLabels returns label names sorted.
+func (m Metadata) sortedLabels() []string {
+	names := make([]string, 0, len(m.Labels))
+	for name := range m.Labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Described is implemented by roles and permissions carrying metadata.
+type Described interface {
+	Metadata() Metadata
+}
+
+// MetadataOf returns metadata of a role or a permission,
+// an empty one if `v` doesn't implement Described.
+func MetadataOf(v interface{}) Metadata {
+	if d, ok := v.(Described); ok {
+		return d.Metadata()
+	}
+	return Metadata{}
+}
+
+// DescribedPermission is a permission carrying metadata.
+// Matching sees the wrapped permission.
+type DescribedPermission struct {
+	Permission
+	Meta Metadata
+}
+
+// Describe returns `p` carrying the metadata `m`.
+func Describe(p Permission, m Metadata) *DescribedPermission {
+	return &DescribedPermission{Permission: p, Meta: m.clone()}
+}
+
+// Metadata returns a copy of the permission's metadata.
+func (p *DescribedPermission) Metadata() Metadata {
+	return p.Meta.clone()
+}
+
+type describedRole interface {
+	SetMetadata(Metadata) *SimpleRole
+}
+
+// SetMetadata replaces metadata of the role `id`.
+// The role has to implement SetMetadata as SimpleRole does.
+func (rbac *RBAC) SetMetadata(id string, m Metadata) error {
+	rbac.mutex.Lock()
+	role, ok := rbac.roles[id]
+	if !ok {
+		rbac.mutex.Unlock()
+		return roleNotExist("SetMetadata", id)
+	}
+	dr, ok := role.(describedRole)
+	if !ok {
+		rbac.mutex.Unlock()
+		return ErrRoleImmutable
+	}
+	dr.SetMetadata(m)
+	m = m.clone()
+	rbac.emit(Event{Op: EventSetMetadata, Role: id, Metadata: &m})
+	rbac.mutex.Unlock()
+	rbac.notify()
+	return nil
+}
+
+// Requirement is a single condition of a Selector.
+type Requirement struct {
+	Label string
+	// Value is compared unless only the existence of the label is required.
+	Value string
+	// Exists requires the label to be set (or not set, if Negate) regardless of its value.
+	Exists bool
+	// Negate inverts the requirement.
+	Negate bool
+}
+
+// Matches returns true if `labels` satisfy the requirement.
+func (r Requirement) Matches(labels map[string]string) bool {
+	v, ok := labels[r.Label]
+	if r.Exists {
+		return ok != r.Negate
+	}
+	return (ok && v == r.Value) != r.Negate
+}
+
+func (r Requirement) String() string {
+	switch {
+	case r.Exists && r.Negate:
+		return "!" + r.Label
+	case r.Exists:
+		return r.Label
+	case r.Negate:
+		return r.Label + "!=" + r.Value
+	}
+	return r.Label + "=" + r.Value
+}
+
+// Selector matches labels satisfying all of its requirements.
+// An empty Selector matches everything.
+type Selector []Requirement
+
+// SelectLabels returns the selector requiring all of `labels`.
+func SelectLabels(labels map[string]string) Selector {
+	sel := make(Selector, 0, len(labels))
+	for _, name := range (Metadata{Labels: labels}).sortedLabels() {
+		sel = append(sel, Requirement{Label: name, Value: labels[name]})
+	}
+	return sel
+}
+
+// ParseSelector parses comma-separated requirements:
+// `name=value`, `name!=value`, `name` (the label is set) and `!name` (the label is not set),
+// e.g. `team=billing,tier!=free`.
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		var r Requirement
+		switch {
+		case strings.Contains(part, "!="):
+			i := strings.Index(part, "!=")
+			r = Requirement{Label: part[:i], Value: part[i+2:], Negate: true}
+		case strings.Contains(part, "="):
+			i := strings.Index(part, "=")
+			r = Requirement{Label: part[:i], Value: part[i+1:]}
+		case strings.HasPrefix(part, "!"):
+			r = Requirement{Label: part[1:], Exists: true, Negate: true}
+		default:
+			r = Requirement{Label: part, Exists: true}
+		}
+		r.Label = strings.TrimSpace(r.Label)
+		r.Value = strings.TrimSpace(r.Value)
+		if r.Label == "" || strings.ContainsAny(r.Label, "=!") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidSelector, part)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// Matches returns true if `labels` satisfy all requirements.
+func (sel Selector) Matches(labels map[string]string) bool {
+	for _, r := range sel {
+		if !r.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (sel Selector) String() string {
+	parts := make([]string, len(sel))
+	for i, r := range sel {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Select returns the sorted ids of roles whose labels match `sel`.
+func (rbac *RBAC) Select(sel Selector) []string {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	var result []string
+	for id, role := range rbac.roles {
+		if sel.Matches(MetadataOf(role).Labels) {
+			result = append(result, id)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// WalkSelected passes each Role whose labels match `sel` to WalkHandler.
+func WalkSelected(rbac *RBAC, sel Selector, h WalkHandler) error {
+	if h == nil {
+		return nil
+	}
+	return Walk(rbac, func(r Role, parents []string) error {
+		if !sel.Matches(MetadataOf(r).Labels) {
+			return nil
+		}
+		return h(r, parents)
+	})
+}
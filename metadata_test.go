@@ -0,0 +1,113 @@
+package gorbac
+
+import (
+	"errors"
+	"testing"
+)
+
+func prepareMetadata(t *testing.T) *RBAC {
+	rbac := prepareTree(t)
+	assert(t, rbac.SetMetadata("admin", Metadata{Title: "Administrator", Labels: map[string]string{"team": "platform", "tier": "gold"}}))
+	assert(t, rbac.SetMetadata("moderator", Metadata{Labels: map[string]string{"team": "billing"}}))
+	assert(t, rbac.SetMetadata("reporter", Metadata{Labels: map[string]string{"team": "billing", "tier": "free"}}))
+	return rbac
+}
+
+func TestRBAC_SetMetadata(t *testing.T) {
+	rbac := prepareTree(t)
+	var events []Event
+	rbac.OnChange(func(e Event) {
+		events = append(events, e)
+	})
+
+	labels := map[string]string{"owner": "ops"}
+	assert(t, rbac.SetMetadata("root", Metadata{Title: "Root", Description: "Everything", Labels: labels}))
+	labels["owner"] = "changed"
+	role, _, err := rbac.GetRole("root")
+	assert(t, err)
+	if m := MetadataOf(role); m.Title != "Root" || m.Labels["owner"] != "ops" {
+		t.Fatalf("Unexpected metadata: %+v", m)
+	}
+	if err := rbac.SetMetadata("not-exist", Metadata{}); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatalf("%s needed", ErrRoleNotExist)
+	}
+
+	replica := prepareTree(t)
+	assert(t, replica.Apply(events...))
+	role, _, err = replica.GetRole("root")
+	assert(t, err)
+	if m := MetadataOf(role); m.Description != "Everything" {
+		t.Fatalf("Metadata should be replayed: %+v", m)
+	}
+}
+
+func TestDescribedPermission(t *testing.T) {
+	rbac := prepareTree(t)
+	p := Describe(NewDeepPermission("report"), Metadata{Title: "Reports", Labels: map[string]string{"group": "analytics"}})
+	assert(t, rbac.Assign("admin", p))
+
+	if !rbac.IsGranted("moderator", NewDeepPermission("report:monthly"), nil) {
+		t.Fatal("Described permission should match as the wrapped one")
+	}
+	if !rbac.IsGranted("root", Describe(NewDeepPermission("system:halt"), Metadata{}), nil) {
+		t.Fatal("Described permission should be checked as the wrapped one")
+	}
+	if MetadataOf(rbac.Permissions("admin")["report"]).Title != "Reports" {
+		t.Fatal("Metadata of the permission should be kept")
+	}
+
+	policy, err := rbac.Export()
+	assert(t, err)
+	cp, err := NewFromPolicy(policy)
+	assert(t, err)
+	if m := MetadataOf(cp.Permissions("admin")["report"]); m.Labels["group"] != "analytics" {
+		t.Fatalf("Metadata should survive the policy: %+v", m)
+	}
+}
+
+func TestParseSelector(t *testing.T) {
+	sel, err := ParseSelector("team=billing, tier!=gold,owner,!deprecated")
+	assert(t, err)
+	if sel.String() != "team=billing,tier!=gold,owner,!deprecated" {
+		t.Fatalf("Unexpected selector: %s", sel)
+	}
+	if !sel.Matches(map[string]string{"team": "billing", "owner": "ops"}) {
+		t.Fatal("Labels should match")
+	}
+	if sel.Matches(map[string]string{"team": "billing", "owner": "ops", "deprecated": "yes"}) {
+		t.Fatal("Deprecated labels should not match")
+	}
+	if sel.Matches(map[string]string{"team": "billing", "tier": "gold", "owner": "ops"}) {
+		t.Fatal("Gold tier should not match")
+	}
+	for _, s := range []string{"=billing", "!", "a!b"} {
+		if _, err := ParseSelector(s); !errors.Is(err, ErrInvalidSelector) {
+			t.Fatalf("%s needed for %q", ErrInvalidSelector, s)
+		}
+	}
+}
+
+func TestRBAC_Select(t *testing.T) {
+	rbac := prepareMetadata(t)
+
+	if list := rbac.Select(SelectLabels(map[string]string{"team": "billing"})); !equalStrings(list, []string{"moderator", "reporter"}) {
+		t.Fatalf("Unexpected roles: %v", list)
+	}
+	sel, err := ParseSelector("tier")
+	assert(t, err)
+	if list := rbac.Select(sel); !equalStrings(list, []string{"admin", "reporter"}) {
+		t.Fatalf("Unexpected roles: %v", list)
+	}
+	if list := rbac.Select(nil); len(list) != 5 {
+		t.Fatalf("Empty selector should match all roles: %v", list)
+	}
+
+	var walked []string
+	assert(t, WalkSelected(rbac, SelectLabels(map[string]string{"team": "billing"}), func(r Role, _ []string) error {
+		walked = append(walked, r.ID())
+		return nil
+	}))
+	if !equalStrings(walked, []string{"moderator", "reporter"}) {
+		t.Fatalf("Unexpected roles: %v", walked)
+	}
+}
@@ -0,0 +1,102 @@
+package gorbac
+
+import "time"
+
+// Metrics receives measurements of authorization checks.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	// ObserveCheck is called once for every check with its operation name
+	// (IsGranted, AnyGranted, AllGranted, CheckMany, Query or Filter),
+	// the permission id, the result and the latency.
+	// CheckMany reports every permission with an equal share of the batch latency.
+	// Permission ids may include instance ids, e.g. `doc:42:read`,
+	// so implementations labeling by them should bound the number of distinct values.
+	ObserveCheck(op, permission string, granted bool, latency time.Duration)
+}
+
+// NewMetricsLogger returns a DecisionLogger reporting every decision to `m`.
+func NewMetricsLogger(m Metrics) DecisionLogger {
+	return DecisionLoggerFunc(func(d Decision) {
+		m.ObserveCheck(d.Op, d.Permission, d.Granted, d.Latency)
+	})
+}
+
+// Instrument reports every check of `rbac` to `m`, replacing Metrics registered before.
+// It is independent of the DecisionLogger, a nil `m` stops reporting.
+func Instrument(rbac *RBAC, m Metrics) {
+	rbac.mutex.Lock()
+	rbac.metrics = m
+	rbac.mutex.Unlock()
+}
+
+// checkTimer measures a check for the registered Metrics, if any.
+type checkTimer struct {
+	m     Metrics
+	start time.Time
+}
+
+// timer starts measuring a check.
+// The lock has to be held by the caller.
+func (rbac *RBAC) timer() checkTimer {
+	if rbac.metrics == nil {
+		return checkTimer{}
+	}
+	return checkTimer{m: rbac.metrics, start: time.Now()}
+}
+
+// done reports the check of `p` to the Metrics.
+func (t checkTimer) done(op string, p Permission, granted bool) {
+	if t.m != nil {
+		t.m.ObserveCheck(op, permissionID(p), granted, time.Since(t.start))
+	}
+}
+
+// doneMany reports checks of `perms` sharing the measured latency to the Metrics.
+func (t checkTimer) doneMany(op string, perms []Permission, granted []bool) {
+	if t.m == nil || len(perms) == 0 {
+		return
+	}
+	latency := time.Since(t.start) / time.Duration(len(perms))
+	for i, p := range perms {
+		t.m.ObserveCheck(op, permissionID(p), granted[i], latency)
+	}
+}
+
+func permissionID(p Permission) string {
+	if p == nil {
+		return ""
+	}
+	return p.ID()
+}
+
+// Stats describes the size of a policy.
+type Stats struct {
+	// Roles is the number of roles.
+	Roles int
+	// Edges is the number of parent bindings.
+	Edges int
+	// Permissions is the number of distinct permission ids assigned to roles.
+	Permissions int
+	// Assignments is the number of permissions assigned to roles.
+	Assignments int
+}
+
+// Stats returns the size of the policy.
+func (rbac *RBAC) Stats() Stats {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+
+	s := Stats{Roles: len(rbac.roles)}
+	for _, parents := range rbac.parents {
+		s.Edges += len(parents)
+	}
+	ids := make(map[string]struct{})
+	for _, role := range rbac.roles {
+		for _, p := range role.Permissions() {
+			ids[p.ID()] = empty
+			s.Assignments++
+		}
+	}
+	s.Permissions = len(ids)
+	return s
+}
@@ -0,0 +1,58 @@
+package gorbac
+
+import (
+	"testing"
+	"time"
+)
+
+type countingMetrics struct {
+	checks map[string]int
+}
+
+func (m *countingMetrics) ObserveCheck(op, permission string, granted bool, latency time.Duration) {
+	if granted {
+		m.checks[op+" "+permission+" allow"]++
+	} else {
+		m.checks[op+" "+permission+" deny"]++
+	}
+}
+
+func TestInstrument(t *testing.T) {
+	rbac, list := prepareDecisions(t)
+	m := &countingMetrics{checks: make(map[string]int)}
+	Instrument(rbac, m)
+
+	rbac.IsGranted("decision-2", NewDeepPermission("task:read"), nil)
+	rbac.IsGranted("decision-2", NewDeepPermission("task:read"), nil)
+	AllGranted(rbac, []string{"decision-1", "decision-2"}, NewDeepPermission("user:read"), nil)
+
+	if m.checks["IsGranted task:read allow"] != 2 || m.checks["AllGranted user:read deny"] != 1 {
+		t.Fatalf("Unexpected metrics: %v", m.checks)
+	}
+	if len(*list) != 3 {
+		t.Fatal("The previous logger should be kept")
+	}
+
+	rbac.SetDecisionLogger(nil)
+	rbac.IsGranted("decision-2", NewDeepPermission("task:read"), nil)
+	rbac.CheckMany([]string{"decision-2"}, []Permission{NewDeepPermission("task:read")}, nil)
+	Filter(rbac, []string{"decision-2"}, []string{"task:read"}, func(id string) Permission { return NewDeepPermission(id) })
+	if m.checks["IsGranted task:read allow"] != 3 || m.checks["CheckMany task:read allow"] != 1 || m.checks["Filter task:read allow"] != 1 {
+		t.Fatalf("Metrics should not depend on the logger: %v", m.checks)
+	}
+
+	Instrument(rbac, nil)
+	rbac.IsGranted("decision-2", NewDeepPermission("task:read"), nil)
+	if m.checks["IsGranted task:read allow"] != 3 {
+		t.Fatal("Metrics should be detached")
+	}
+}
+
+func TestRBAC_Stats(t *testing.T) {
+	rbac, _ := prepareDecisions(t)
+
+	s := rbac.Stats()
+	if s.Roles != 2 || s.Edges != 1 || s.Permissions != 2 || s.Assignments != 2 {
+		t.Fatalf("Unexpected stats: %+v", s)
+	}
+}
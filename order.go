@@ -0,0 +1,118 @@
+package gorbac
+
+import "sort"
+
+// Option configures a RBAC structure created by New.
+type Option func(*RBAC)
+
+// WithSortedOutput makes GetRoles, GetRole, GetParents, Walk, Children, Ancestors,
+// Descendants, Roots, Leaves, RolesWithPermission and SubjectRoles
+// return roles and parents ordered by id, so results are stable between runs.
+func WithSortedOutput() Option {
+	return func(rbac *RBAC) {
+		rbac.sorted = true
+	}
+}
+
+// SortRoles orders `roles` by id in place and returns them.
+func SortRoles(roles []Role) []Role {
+	sort.Slice(roles, func(i, j int) bool { return roles[i].ID() < roles[j].ID() })
+	return roles
+}
+
+// SortPermissions orders `list` by id in place and returns it.
+func SortPermissions(list []Permission) []Permission {
+	sort.Slice(list, func(i, j int) bool { return list[i].ID() < list[j].ID() })
+	return list
+}
+
+// Sorted returns the permissions ordered by id.
+func (ps Permissions) Sorted() []Permission {
+	list := make([]Permission, 0, len(ps))
+	for _, p := range ps {
+		list = append(list, p)
+	}
+	return SortPermissions(list)
+}
+
+// SortedPermissions returns all permissions of the role ordered by id.
+func (role *SimpleRole) SortedPermissions() []Permission {
+	return SortPermissions(role.Permissions())
+}
+
+// sortIDs orders `ids` if sorted output is configured.
+func (rbac *RBAC) sortIDs(ids []string) []string {
+	if rbac.sorted {
+		sort.Strings(ids)
+	}
+	return ids
+}
+
+// WalkTopological passes each Role to WalkHandler in topological order of the parent graph:
+// parents before children, roles of the same level ordered by id.
+// If the graph has a circle, a RoleError wrapping ErrFoundCircle and listing the roles
+// which are on or behind a circle is returned and no role is visited.
+// The lock is not held while `h` is running.
+func WalkTopological(rbac *RBAC, h WalkHandler) error {
+	if h == nil {
+		return nil
+	}
+	rbac.mutex.RLock()
+	roles := make([]Role, 0, len(rbac.roles))
+	parents := make(map[string][]string, len(rbac.roles))
+	pending := make(map[string]int, len(rbac.roles))
+	var ready []string
+	for id := range rbac.roles {
+		for parent := range rbac.parents[id] {
+			if _, ok := rbac.roles[parent]; ok {
+				parents[id] = append(parents[id], parent)
+			}
+		}
+		sort.Strings(parents[id])
+		pending[id] = len(parents[id])
+		if pending[id] == 0 {
+			ready = append(ready, id)
+		}
+	}
+	children := make(map[string][]string, len(rbac.children))
+	for id, ids := range rbac.children {
+		for child := range ids {
+			children[id] = append(children[id], child)
+		}
+	}
+
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		var next []string
+		for _, id := range ready {
+			roles = append(roles, rbac.roles[id])
+			delete(pending, id)
+			for _, child := range children[id] {
+				if _, ok := pending[child]; !ok {
+					continue
+				}
+				pending[child]--
+				if pending[child] == 0 {
+					next = append(next, child)
+				}
+			}
+		}
+		ready = next
+	}
+	rbac.mutex.RUnlock()
+
+	if len(pending) > 0 {
+		ids := make([]string, 0, len(pending))
+		for id := range pending {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		return &RoleError{Op: "WalkTopological", IDs: ids, Err: ErrFoundCircle}
+	}
+	for _, r := range roles {
+		if err := h(r, parents[r.ID()]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package gorbac
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRBAC_SortedOutput(t *testing.T) {
+	rbac := New(WithSortedOutput())
+	for _, id := range []string{"delta", "alpha", "charlie", "bravo"} {
+		assert(t, rbac.Add(NewRole(id)))
+	}
+	assert(t, rbac.SetParents("alpha", []string{"delta", "bravo", "charlie"}))
+
+	var ids []string
+	for _, r := range rbac.GetRoles() {
+		ids = append(ids, r.ID())
+	}
+	if !equalStrings(ids, []string{"alpha", "bravo", "charlie", "delta"}) {
+		t.Fatalf("Unexpected roles: %v", ids)
+	}
+	if parents, _ := rbac.GetParents("alpha"); !equalStrings(parents, []string{"bravo", "charlie", "delta"}) {
+		t.Fatalf("Unexpected parents: %v", parents)
+	}
+	if _, parents, _ := rbac.GetRole("alpha"); !equalStrings(parents, []string{"bravo", "charlie", "delta"}) {
+		t.Fatalf("Unexpected parents: %v", parents)
+	}
+
+	ids = nil
+	assert(t, Walk(rbac, func(r Role, _ []string) error {
+		ids = append(ids, r.ID())
+		return nil
+	}))
+	if !equalStrings(ids, []string{"alpha", "bravo", "charlie", "delta"}) {
+		t.Fatalf("Unexpected walk: %v", ids)
+	}
+}
+
+func TestSortPermissions(t *testing.T) {
+	role := NewRole("role").
+		Assign(NewPermission("c")).
+		Assign(NewPermission("a")).
+		Assign(NewPermission("b"))
+
+	var ids []string
+	for _, p := range role.SortedPermissions() {
+		ids = append(ids, p.ID())
+	}
+	if !equalStrings(ids, []string{"a", "b", "c"}) {
+		t.Fatalf("Unexpected permissions: %v", ids)
+	}
+
+	rbac := prepareTree(t)
+	ids = nil
+	for _, p := range rbac.Permissions("observer").Sorted() {
+		ids = append(ids, p.ID())
+	}
+	if !equalStrings(ids, []string{"system", "task", "task:read", "user"}) {
+		t.Fatalf("Unexpected permissions: %v", ids)
+	}
+}
+
+func TestWalkTopological(t *testing.T) {
+	rbac := prepareTree(t)
+	assert(t, rbac.Add(NewRole("guest")))
+	assert(t, rbac.SetParent("reporter", "guest"))
+
+	var ids []string
+	assert(t, WalkTopological(rbac, func(r Role, parents []string) error {
+		if r.ID() == "reporter" && !equalStrings(parents, []string{"guest", "moderator"}) {
+			t.Fatalf("Unexpected parents: %v", parents)
+		}
+		ids = append(ids, r.ID())
+		return nil
+	}))
+	if !equalStrings(ids, []string{"guest", "root", "admin", "moderator", "observer", "reporter"}) {
+		t.Fatalf("Unexpected order: %v", ids)
+	}
+
+	stop := errors.New("stop")
+	if err := WalkTopological(rbac, func(Role, []string) error { return stop }); err != stop {
+		t.Fatalf("Handler error expected, but %v got", err)
+	}
+
+	assert(t, rbac.SetParent("root", "moderator"))
+	visited := false
+	err := WalkTopological(rbac, func(Role, []string) error {
+		visited = true
+		return nil
+	})
+	var re *RoleError
+	if !errors.Is(err, ErrFoundCircle) || !errors.As(err, &re) ||
+		!equalStrings(re.IDs, []string{"admin", "moderator", "observer", "reporter", "root"}) || visited {
+		t.Fatalf("%s needed, but %v got", ErrFoundCircle, err)
+	}
+}
@@ -37,6 +37,7 @@ func (p *SimplePermission) Match(a Permission) bool {
 // If the Id is matched, it can be considered having the permission.
 // Otherwise, it checks every layers of permission.
 // A role which has an upper layer granted, will be granted sub-layers permissions.
+// ResourcePermission is matched by the layers of its ID.
 type DeepPermission struct {
 	IDStr string `json:"id"`
 	Sep   string `json:"sep"`
@@ -57,12 +58,16 @@ func (p *DeepPermission) Match(a Permission) bool {
 	if p.IDStr == a.ID() {
 		return true
 	}
-	q, ok := a.(*DeepPermission)
-	if !ok {
+	var qLayers []string
+	switch q := unbind(a).(type) {
+	case *DeepPermission:
+		qLayers = strings.Split(q.IDStr, q.Sep)
+	case *ResourcePermission:
+		qLayers = strings.Split(q.ID(), q.Sep)
+	default:
 		return false
 	}
 	pLayers := strings.Split(p.IDStr, p.Sep)
-	qLayers := strings.Split(q.IDStr, q.Sep)
 
 	if len(pLayers) > len(qLayers) {
 		return false
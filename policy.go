@@ -0,0 +1,312 @@
+package gorbac
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Permission kinds stored in a PermissionRecord.
+const (
+	KindSimple   = "simple"
+	KindDeep     = "deep"
+	KindResource = "resource"
+	KindTemplate = "template"
+)
+
+// ErrUnknownPermissionKind occurred if a permission can't be converted to or from a record
+var ErrUnknownPermissionKind = errors.New("unknown permission kind")
+
+// PermissionRecord is the serializable form of a permission.
+// Metadata of a DescribedPermission is kept.
+type PermissionRecord struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"`
+	Sep  string `json:"sep,omitempty"`
+	// Resource and Action keep both parts of a resource permission;
+	// if both are empty, they are parsed from ID by DefaultActions.
+	Resource string `json:"resource,omitempty"`
+	Action   string `json:"action,omitempty"`
+	Metadata
+}
+
+// NewPermissionRecord returns the record of `p`.
+func NewPermissionRecord(p Permission) (PermissionRecord, error) {
+	switch v := p.(type) {
+	case *SimplePermission:
+		return PermissionRecord{ID: v.IDStr, Kind: KindSimple}, nil
+	case *DeepPermission:
+		return PermissionRecord{ID: v.IDStr, Kind: KindDeep, Sep: v.Sep}, nil
+	case *ResourcePermission:
+		return PermissionRecord{ID: v.ID(), Kind: KindResource, Sep: v.Sep, Resource: strings.Join(v.Resource, v.Sep), Action: v.Action}, nil
+	case *TemplatePermission:
+		return PermissionRecord{ID: v.IDStr, Kind: KindTemplate, Sep: v.Sep}, nil
+	case *DescribedPermission:
+		r, err := NewPermissionRecord(v.Permission)
+		if err != nil {
+			return r, err
+		}
+		r.Metadata = v.Meta.clone()
+		return r, nil
+	}
+	return PermissionRecord{}, fmt.Errorf("%w: %T", ErrUnknownPermissionKind, p)
+}
+
+// Permission returns the permission described by the record.
+// Actions of a resource permission recorded without Resource and Action
+// are detected by DefaultActions.
+// A permission with metadata is returned as DescribedPermission.
+func (r PermissionRecord) Permission() (Permission, error) {
+	p, err := r.permission()
+	if err != nil || r.Metadata.IsZero() {
+		return p, err
+	}
+	return Describe(p, r.Metadata), nil
+}
+
+func (r PermissionRecord) permission() (Permission, error) {
+	switch r.Kind {
+	case KindSimple:
+		return &SimplePermission{IDStr: r.ID}, nil
+	case KindDeep:
+		return &DeepPermission{IDStr: r.ID, Sep: r.sep()}, nil
+	case KindResource:
+		if r.Resource != "" || r.Action != "" {
+			return &ResourcePermission{Resource: splitLayers(r.Resource, r.sep()), Action: r.Action, Sep: r.sep()}, nil
+		}
+		return ResourcePermissionFromDeep(&DeepPermission{IDStr: r.ID, Sep: r.sep()}, nil), nil
+	case KindTemplate:
+		p := &TemplatePermission{IDStr: r.ID, Sep: r.sep()}
+		if err := p.parse(); err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownPermissionKind, r.Kind)
+}
+
+func (r PermissionRecord) sep() string {
+	if r.Sep == "" {
+		return ":"
+	}
+	return r.Sep
+}
+
+// RoleRecord is the serializable form of a role with its parents and metadata.
+type RoleRecord struct {
+	ID          string             `json:"id"`
+	Permissions []PermissionRecord `json:"permissions,omitempty"`
+	Parents     []string           `json:"parents,omitempty"`
+	// Edges hold options of parent edges having any, sorted by parent.
+	Edges []EdgeRecord `json:"edges,omitempty"`
+	// Depth is the inheritance limit of the role, 0 if there is none.
+	Depth int `json:"depth,omitempty"`
+	Metadata
+}
+
+// EdgeRecord is the serializable form of options of a parent edge.
+type EdgeRecord struct {
+	Parent string             `json:"parent"`
+	Depth  int                `json:"depth,omitempty"`
+	Filter []PermissionRecord `json:"filter,omitempty"`
+}
+
+func (r EdgeRecord) options() ([]EdgeOption, error) {
+	filter, err := recordPermissions(r.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return []EdgeOption{InheritDepth(r.Depth), InheritOnly(filter...)}, nil
+}
+
+// recordPermissions returns permissions described by `records`.
+func recordPermissions(records []PermissionRecord) ([]Permission, error) {
+	var result []Permission
+	for _, r := range records {
+		p, err := r.Permission()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, p)
+	}
+	return result, nil
+}
+
+// Policy is the serializable form of the whole RBAC.
+type Policy struct {
+	Roles []RoleRecord `json:"roles"`
+	// Subjects maps subjects to their roles.
+	Subjects map[string][]string `json:"subjects,omitempty"`
+}
+
+// Export returns the policy of `rbac`, roles, permissions and parents are sorted by id.
+func (rbac *RBAC) Export() (*Policy, error) {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+
+	policy := &Policy{Roles: make([]RoleRecord, 0, len(rbac.roles))}
+	for id, role := range rbac.roles {
+		record := RoleRecord{ID: id, Depth: rbac.depths[id], Metadata: MetadataOf(role)}
+		for _, p := range role.Permissions() {
+			pr, err := NewPermissionRecord(p)
+			if err != nil {
+				return nil, err
+			}
+			record.Permissions = append(record.Permissions, pr)
+		}
+		sort.Slice(record.Permissions, func(i, j int) bool {
+			return record.Permissions[i].ID < record.Permissions[j].ID
+		})
+		for parent := range rbac.parents[id] {
+			record.Parents = append(record.Parents, parent)
+		}
+		sort.Strings(record.Parents)
+		for _, parent := range record.Parents {
+			if e, ok := rbac.edges[edgeKey{id, parent}]; ok {
+				er := EdgeRecord{Parent: parent, Depth: e.depth}
+				for _, p := range e.filter {
+					pr, err := NewPermissionRecord(p)
+					if err != nil {
+						return nil, err
+					}
+					er.Filter = append(er.Filter, pr)
+				}
+				sort.Slice(er.Filter, func(i, j int) bool {
+					return er.Filter[i].ID < er.Filter[j].ID
+				})
+				record.Edges = append(record.Edges, er)
+			}
+		}
+		policy.Roles = append(policy.Roles, record)
+	}
+	sort.Slice(policy.Roles, func(i, j int) bool {
+		return policy.Roles[i].ID < policy.Roles[j].ID
+	})
+	for subject, roles := range rbac.subjects {
+		if policy.Subjects == nil {
+			policy.Subjects = make(map[string][]string, len(rbac.subjects))
+		}
+		for id := range roles {
+			policy.Subjects[subject] = append(policy.Subjects[subject], id)
+		}
+		sort.Strings(policy.Subjects[subject])
+	}
+	return policy, nil
+}
+
+// Import adds roles of the policy into `rbac` as SimpleRole with their metadata and inheritance limits,
+// binds their parents and assigns subjects.
+// If a role is existing or a parent or a subject role is missing, an error will be returned
+// and `rbac` is left unchanged.
+func (rbac *RBAC) Import(policy *Policy) error {
+	roles := make(Roles, len(policy.Roles))
+	options := make(map[edgeKey][]EdgeOption)
+	for _, record := range policy.Roles {
+		for _, e := range record.Edges {
+			opts, err := e.options()
+			if err != nil {
+				return err
+			}
+			options[edgeKey{record.ID, e.Parent}] = opts
+		}
+		if _, ok := roles[record.ID]; ok {
+			return roleExist("Import", record.ID)
+		}
+		role := NewRole(record.ID).SetMetadata(record.Metadata)
+		for _, pr := range record.Permissions {
+			p, err := pr.Permission()
+			if err != nil {
+				return err
+			}
+			role.Assign(p)
+		}
+		roles[record.ID] = role
+	}
+
+	rbac.mutex.Lock()
+	var existing, missing []string
+	for _, record := range policy.Roles {
+		if _, ok := rbac.roles[record.ID]; ok {
+			existing = append(existing, record.ID)
+		}
+	}
+	if len(existing) > 0 {
+		rbac.mutex.Unlock()
+		return roleExist("Import", existing...)
+	}
+	if err := rbac.declaredRoles(roles); err != nil {
+		rbac.mutex.Unlock()
+		return err
+	}
+	seen := make(map[string]struct{})
+	check := func(id string) {
+		_, ok := roles[id]
+		_, exist := rbac.roles[id]
+		_, found := seen[id]
+		if !ok && !exist && !found {
+			seen[id] = empty
+			missing = append(missing, id)
+		}
+	}
+	for _, record := range policy.Roles {
+		for _, parent := range record.Parents {
+			check(parent)
+		}
+		for _, e := range record.Edges {
+			check(e.Parent)
+		}
+	}
+	for _, ids := range policy.Subjects {
+		for _, id := range ids {
+			check(id)
+		}
+	}
+	if len(missing) > 0 {
+		rbac.mutex.Unlock()
+		sort.Strings(missing)
+		return roleNotExist("Import", missing...)
+	}
+	var events []Event
+	for _, record := range policy.Roles {
+		rbac.roles[record.ID] = roles[record.ID]
+		events = append(events, Event{Op: EventAddRole, Role: record.ID, Permissions: record.Permissions, Metadata: roleMetadata(roles[record.ID])})
+	}
+	for _, record := range policy.Roles {
+		if record.Depth > 0 {
+			rbac.depths[record.ID] = record.Depth
+			events = append(events, Event{Op: EventSetDepth, Role: record.ID, Depth: record.Depth})
+		}
+		for _, parent := range record.Parents {
+			key := edgeKey{record.ID, parent}
+			events = append(events, edgeEvent(record.ID, parent, rbac.setEdge(record.ID, parent, options[key])))
+			delete(options, key)
+		}
+		for _, e := range record.Edges {
+			key := edgeKey{record.ID, e.Parent}
+			if opts, ok := options[key]; ok {
+				events = append(events, edgeEvent(record.ID, e.Parent, rbac.setEdge(record.ID, e.Parent, opts)))
+				delete(options, key)
+			}
+		}
+	}
+	for subject, ids := range policy.Subjects {
+		for _, id := range ids {
+			rbac.assignSubject(subject, id)
+			events = append(events, Event{Op: EventAssignSubject, Role: id, Subject: subject})
+		}
+	}
+	rbac.emit(events...)
+	rbac.mutex.Unlock()
+	rbac.notify()
+	return nil
+}
+
+// NewFromPolicy returns a RBAC structure filled by the policy.
+func NewFromPolicy(policy *Policy) (*RBAC, error) {
+	rbac := New()
+	if err := rbac.Import(policy); err != nil {
+		return nil, err
+	}
+	return rbac, nil
+}
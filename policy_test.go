@@ -0,0 +1,86 @@
+package gorbac
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func equalPolicies(a, b *Policy) bool {
+	ta, _ := json.Marshal(a)
+	tb, _ := json.Marshal(b)
+	return string(ta) == string(tb)
+}
+
+type customPermission struct {
+	SimplePermission
+}
+
+func TestPermissionRecord(t *testing.T) {
+	for _, p := range []Permission{
+		NewPermission("simple"),
+		NewDeepPermission("deep:read"),
+		&DeepPermission{IDStr: "doc/read", Sep: "/"},
+		NewResourcePermission("invoice:42", "read"),
+		NewResourcePermission("invoice", "approve"),
+		NewResourcePermission("invoice:read", ""),
+		MustTemplatePermission("user:{subject}"),
+	} {
+		r, err := NewPermissionRecord(p)
+		assert(t, err)
+		cp, err := r.Permission()
+		assert(t, err)
+		if cp.ID() != p.ID() || !cp.Match(p) {
+			t.Errorf("%s: permission changed by the record %+v", p.ID(), r)
+		}
+		if rp, ok := p.(*ResourcePermission); ok {
+			if q := cp.(*ResourcePermission); q.Action != rp.Action || !equalStrings(q.Resource, rp.Resource) {
+				t.Errorf("%s: resource or action changed by the record %+v", p.ID(), r)
+			}
+		}
+	}
+
+	legacy, err := (PermissionRecord{ID: "invoice:42:write", Kind: KindResource}).Permission()
+	assert(t, err)
+	if rp := legacy.(*ResourcePermission); rp.Action != "write" || !equalStrings(rp.Resource, []string{"invoice", "42"}) {
+		t.Fatalf("Action of a legacy record should be parsed: %+v", rp)
+	}
+
+	if _, err := NewPermissionRecord(&customPermission{}); !errors.Is(err, ErrUnknownPermissionKind) {
+		t.Fatalf("%s needed", ErrUnknownPermissionKind)
+	}
+	if _, err := (PermissionRecord{ID: "x", Kind: "custom"}).Permission(); !errors.Is(err, ErrUnknownPermissionKind) {
+		t.Fatalf("%s needed", ErrUnknownPermissionKind)
+	}
+}
+
+func TestRBAC_ExportImport(t *testing.T) {
+	rbac := prepareTree(t)
+
+	policy, err := rbac.Export()
+	assert(t, err)
+	if len(policy.Roles) != 5 || policy.Roles[0].ID != "admin" || policy.Roles[0].Parents[0] != "root" {
+		t.Fatalf("Unexpected policy: %+v", policy)
+	}
+
+	text, err := json.Marshal(policy)
+	assert(t, err)
+	var decoded Policy
+	assert(t, json.Unmarshal(text, &decoded))
+
+	cp, err := NewFromPolicy(&decoded)
+	assert(t, err)
+	if !cp.IsGranted("observer", NewDeepPermission("user:delete"), nil) {
+		t.Fatal("Imported hierarchy should be kept")
+	}
+
+	if err := cp.Import(&Policy{Roles: []RoleRecord{{ID: "admin"}}}); !errors.Is(err, ErrRoleExist) {
+		t.Fatalf("%s needed", ErrRoleExist)
+	}
+	if err := cp.Import(&Policy{Roles: []RoleRecord{{ID: "new", Parents: []string{"none"}}}}); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatalf("%s needed", ErrRoleNotExist)
+	}
+	if _, _, err := cp.GetRole("new"); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatal("Failed import should not change RBAC")
+	}
+}
@@ -0,0 +1,114 @@
+// Package promrbac exposes metrics of gorbac checks to a Prometheus registry.
+package promrbac
+
+import (
+	"time"
+
+	gorbac "github.com/efureev/go.rbac"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics implements gorbac.Metrics on top of Prometheus collectors.
+type Metrics struct {
+	checks   *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+	policy   *policyCollector
+	label    func(string) string
+}
+
+// Option configures Metrics.
+type Option func(*Metrics)
+
+// WithPermissionLabel maps permission ids to values of the permission label,
+// e.g. to strip instance ids from `doc:42:read` or to group permissions.
+// An empty value drops the permission from the labels.
+func WithPermissionLabel(f func(id string) string) Option {
+	return func(m *Metrics) {
+		m.label = f
+	}
+}
+
+// New returns Metrics for `rbac` registered in `reg` and attaches it to `rbac`.
+// If registration fails, nothing stays registered.
+// Check metrics are labeled by op, permission and outcome,
+// policy size gauges are collected from `rbac` on every scrape.
+// Every distinct permission id is a label value by default;
+// if checked ids are unbounded, e.g. include instance ids, use WithPermissionLabel.
+func New(rbac *gorbac.RBAC, reg prometheus.Registerer, opts ...Option) (*Metrics, error) {
+	m := &Metrics{
+		checks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "rbac",
+			Name:      "checks_total",
+			Help:      "Number of authorization checks.",
+		}, []string{"op", "permission", "outcome"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "rbac",
+			Name:      "check_duration_seconds",
+			Help:      "Latency of authorization checks.",
+			Buckets:   prometheus.ExponentialBuckets(1e-7, 4, 10),
+		}, []string{"op", "permission", "outcome"}),
+		policy: newPolicyCollector(rbac),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	collectors := []prometheus.Collector{m.checks, m.duration, m.policy}
+	for i, c := range collectors {
+		if err := reg.Register(c); err != nil {
+			for _, registered := range collectors[:i] {
+				reg.Unregister(registered)
+			}
+			return nil, err
+		}
+	}
+	gorbac.Instrument(rbac, m)
+	return m, nil
+}
+
+// ObserveCheck implements gorbac.Metrics.
+func (m *Metrics) ObserveCheck(op, permission string, granted bool, latency time.Duration) {
+	outcome := "deny"
+	if granted {
+		outcome = "allow"
+	}
+	if m.label != nil {
+		permission = m.label(permission)
+	}
+	m.checks.WithLabelValues(op, permission, outcome).Inc()
+	m.duration.WithLabelValues(op, permission, outcome).Observe(latency.Seconds())
+}
+
+type policyCollector struct {
+	rbac        *gorbac.RBAC
+	roles       *prometheus.Desc
+	edges       *prometheus.Desc
+	permissions *prometheus.Desc
+	assignments *prometheus.Desc
+}
+
+func newPolicyCollector(rbac *gorbac.RBAC) *policyCollector {
+	return &policyCollector{
+		rbac:        rbac,
+		roles:       prometheus.NewDesc("rbac_policy_roles", "Number of roles.", nil, nil),
+		edges:       prometheus.NewDesc("rbac_policy_edges", "Number of parent bindings.", nil, nil),
+		permissions: prometheus.NewDesc("rbac_policy_permissions", "Number of distinct permissions.", nil, nil),
+		assignments: prometheus.NewDesc("rbac_policy_assignments", "Number of permissions assigned to roles.", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *policyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.roles
+	ch <- c.edges
+	ch <- c.permissions
+	ch <- c.assignments
+}
+
+// Collect implements prometheus.Collector.
+func (c *policyCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.rbac.Stats()
+	ch <- prometheus.MustNewConstMetric(c.roles, prometheus.GaugeValue, float64(s.Roles))
+	ch <- prometheus.MustNewConstMetric(c.edges, prometheus.GaugeValue, float64(s.Edges))
+	ch <- prometheus.MustNewConstMetric(c.permissions, prometheus.GaugeValue, float64(s.Permissions))
+	ch <- prometheus.MustNewConstMetric(c.assignments, prometheus.GaugeValue, float64(s.Assignments))
+}
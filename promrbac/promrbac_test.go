@@ -0,0 +1,112 @@
+package promrbac
+
+import (
+	"strings"
+	"testing"
+
+	gorbac "github.com/efureev/go.rbac"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	rbac := gorbac.New()
+	rA := gorbac.NewRole("role-a")
+	rB := gorbac.NewRole("role-b")
+	rA.Assign(gorbac.NewPermission("permission-a"))
+	rB.Assign(gorbac.NewPermission("permission-b")).Assign(gorbac.NewPermission("permission-a"))
+	if err := rbac.Add(rA); err != nil {
+		t.Fatal(err)
+	}
+	if err := rbac.Add(rB); err != nil {
+		t.Fatal(err)
+	}
+	if err := rbac.SetParent("role-a", "role-b"); err != nil {
+		t.Fatal(err)
+	}
+
+	reg := prometheus.NewRegistry()
+	m, err := New(rbac, reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rbac.IsGranted("role-a", gorbac.NewPermission("permission-b"), nil)
+	rbac.IsGranted("role-b", gorbac.NewPermission("permission-c"), nil)
+	gorbac.AnyGranted(rbac, []string{"role-a", "role-b"}, gorbac.NewPermission("permission-a"), nil)
+
+	if v := testutil.ToFloat64(m.checks.WithLabelValues("IsGranted", "permission-b", "allow")); v != 1 {
+		t.Fatalf("1 allowed check expected, but %v got", v)
+	}
+	if v := testutil.ToFloat64(m.checks.WithLabelValues("IsGranted", "permission-c", "deny")); v != 1 {
+		t.Fatalf("1 denied check expected, but %v got", v)
+	}
+
+	expected := `
+# HELP rbac_policy_assignments Number of permissions assigned to roles.
+# TYPE rbac_policy_assignments gauge
+rbac_policy_assignments 3
+# HELP rbac_policy_edges Number of parent bindings.
+# TYPE rbac_policy_edges gauge
+rbac_policy_edges 1
+# HELP rbac_policy_permissions Number of distinct permissions.
+# TYPE rbac_policy_permissions gauge
+rbac_policy_permissions 2
+# HELP rbac_policy_roles Number of roles.
+# TYPE rbac_policy_roles gauge
+rbac_policy_roles 2
+`
+	if err := testutil.GatherAndCompare(reg, strings.NewReader(expected),
+		"rbac_policy_roles", "rbac_policy_edges", "rbac_policy_permissions", "rbac_policy_assignments"); err != nil {
+		t.Fatal(err)
+	}
+
+	if n, err := testutil.GatherAndCount(reg, "rbac_check_duration_seconds"); err != nil {
+		t.Fatal(err)
+	} else if n != 3 {
+		t.Fatalf("3 histogram series expected, but %d got", n)
+	}
+
+	if _, err := New(rbac, reg); err == nil {
+		t.Fatal("Registering twice should fail")
+	}
+}
+
+func TestMetrics_PermissionLabel(t *testing.T) {
+	rbac := gorbac.New()
+	if err := rbac.Add(gorbac.NewRole("reader").Assign(gorbac.NewDeepPermission("doc"))); err != nil {
+		t.Fatal(err)
+	}
+	m, err := New(rbac, prometheus.NewRegistry(), WithPermissionLabel(func(id string) string {
+		return strings.SplitN(id, ":", 2)[0]
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	perms := []gorbac.Permission{gorbac.NewDeepPermission("doc:1:read"), gorbac.NewDeepPermission("doc:2:read")}
+	rbac.CheckMany([]string{"reader"}, perms, nil)
+	if v := testutil.ToFloat64(m.checks.WithLabelValues("CheckMany", "doc", "allow")); v != 2 {
+		t.Fatalf("2 checks expected, but %v got", v)
+	}
+	if n := testutil.CollectAndCount(m.checks); n != 1 {
+		t.Fatalf("1 series expected, but %d got", n)
+	}
+}
+
+func TestMetrics_RegisterFailure(t *testing.T) {
+	rbac := gorbac.New()
+	reg := prometheus.NewRegistry()
+	conflict := prometheus.NewGauge(prometheus.GaugeOpts{Name: "rbac_policy_roles", Help: "Number of roles."})
+	if err := reg.Register(conflict); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(rbac, reg); err == nil {
+		t.Fatal("Registration should fail")
+	}
+
+	reg.Unregister(conflict)
+	if _, err := New(rbac, reg); err != nil {
+		t.Fatalf("Retry should succeed, but %v got", err)
+	}
+}
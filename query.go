@@ -0,0 +1,111 @@
+package gorbac
+
+// GrantQuery checks a permission against several roles under a single lock.
+type GrantQuery struct {
+	Roles      []string
+	Permission Permission
+	Assert     AssertionFunc
+	// AssertOnce evaluates Assert once per query with an empty role id,
+	// rather than once per role, for assertions which don't depend on the role.
+	AssertOnce bool
+	// Quorum is the number of roles which have to grant the permission,
+	// 0 means all of them.
+	Quorum int
+	// Detailed evaluates every role to fill the result,
+	// otherwise the evaluation stops as soon as the outcome is known.
+	Detailed bool
+}
+
+// GrantResult is the outcome of a GrantQuery.
+// Roles which were not evaluated are in neither list.
+type GrantResult struct {
+	Granted bool
+	// GrantedBy are the evaluated roles having the permission, in the order of the query.
+	GrantedBy []string
+	// DeniedBy are the evaluated roles not having the permission, in the order of the query.
+	DeniedBy []string
+}
+
+// Query runs `q` against `rbac`.
+// The decision is logged with the Op "Query" and the first granting role,
+// its assertion outcome is the one of that role, or AssertionFailed
+// if no role granted the permission and the assertion denied any of them.
+func (rbac *RBAC) Query(q GrantQuery) (res GrantResult) {
+	quorum := q.Quorum
+	if quorum <= 0 {
+		quorum = len(q.Roles)
+	}
+
+	rbac.mutex.RLock()
+	rbac.observe(q.Permission)
+	t := rbac.timer()
+	logger := rbac.logger
+	var d Decision
+	if logger != nil {
+		d = newDecision("Query", q.Roles, q.Permission)
+	}
+
+	if q.AssertOnce && q.Assert != nil {
+		if !q.Assert(rbac, "", q.Permission) {
+			d.Assertion = AssertionFailed
+			res.DeniedBy = append(res.DeniedBy, q.Roles...)
+		} else {
+			d.Assertion = AssertionPassed
+		}
+	}
+	if res.DeniedBy == nil {
+		for i, id := range q.Roles {
+			if !q.Detailed && (len(res.GrantedBy) >= quorum || len(res.GrantedBy)+len(q.Roles)-i < quorum) {
+				break
+			}
+			if !q.AssertOnce && q.Assert != nil {
+				if !q.Assert(rbac, id, q.Permission) {
+					if len(res.GrantedBy) == 0 {
+						d.Assertion = AssertionFailed
+					}
+					res.DeniedBy = append(res.DeniedBy, id)
+					continue
+				}
+				if d.Assertion == AssertionNone {
+					d.Assertion = AssertionPassed
+				}
+			}
+			by, ok := rbac.recursionCheck(id, q.Permission)
+			if !ok {
+				res.DeniedBy = append(res.DeniedBy, id)
+				continue
+			}
+			if len(res.GrantedBy) == 0 && logger != nil {
+				d.Role, d.Matched = by, matchedPermission(rbac.roles[by], q.Permission)
+				if q.Assert != nil {
+					d.Assertion = AssertionPassed
+				}
+			}
+			res.GrantedBy = append(res.GrantedBy, id)
+		}
+	}
+	rbac.mutex.RUnlock()
+
+	res.Granted = len(res.GrantedBy) >= quorum
+	t.done("Query", q.Permission, res.Granted)
+	if logger != nil {
+		d.finish(res.Granted)
+		logger.LogDecision(d)
+	}
+	return res
+}
+
+// AnyGrantedDetails works like AnyGranted, but evaluates every role
+// and reports which of them granted or denied the permission.
+func AnyGrantedDetails(rbac *RBAC, roles []string, permission Permission, assert AssertionFunc) GrantResult {
+	if len(roles) == 0 {
+		return GrantResult{}
+	}
+	return rbac.Query(GrantQuery{Roles: roles, Permission: permission, Assert: assert, Quorum: 1, Detailed: true})
+}
+
+// AllGrantedDetails works like AllGranted, but evaluates every role
+// and reports which of them granted or denied the permission.
+func AllGrantedDetails(rbac *RBAC, roles []string, permission Permission, assert AssertionFunc) GrantResult {
+	return rbac.Query(GrantQuery{Roles: roles, Permission: permission, Assert: assert, Detailed: true})
+}
@@ -0,0 +1,97 @@
+package gorbac
+
+import "testing"
+
+func TestRBAC_Query(t *testing.T) {
+	rbac := prepareTree(t)
+	roles := []string{"observer", "reporter", "admin", "root"}
+
+	res := AnyGrantedDetails(rbac, roles, NewDeepPermission("task:create"), nil)
+	if !res.Granted || !equalStrings(res.GrantedBy, []string{"observer", "reporter"}) || !equalStrings(res.DeniedBy, []string{"admin", "root"}) {
+		t.Fatalf("Unexpected result: %+v", res)
+	}
+	res = AllGrantedDetails(rbac, roles, NewDeepPermission("task:create"), nil)
+	if res.Granted || len(res.GrantedBy) != 2 {
+		t.Fatalf("Unexpected result: %+v", res)
+	}
+	if res := AllGrantedDetails(rbac, roles, NewDeepPermission("system"), nil); !res.Granted || len(res.DeniedBy) != 0 {
+		t.Fatalf("Unexpected result: %+v", res)
+	}
+	if res := AnyGrantedDetails(rbac, nil, NewDeepPermission("system"), nil); res.Granted {
+		t.Fatal("No roles should not grant any")
+	}
+
+	res = rbac.Query(GrantQuery{Roles: roles, Permission: NewDeepPermission("task:read"), Quorum: 2, Detailed: true})
+	if !res.Granted || !equalStrings(res.GrantedBy, []string{"observer", "reporter"}) || len(res.DeniedBy) != 2 {
+		t.Fatalf("Unexpected result: %+v", res)
+	}
+	res = rbac.Query(GrantQuery{Roles: roles, Permission: NewDeepPermission("task"), Quorum: 3})
+	if res.Granted || !equalStrings(res.GrantedBy, []string{"observer", "reporter"}) || !equalStrings(res.DeniedBy, []string{"admin", "root"}) {
+		t.Fatalf("Unexpected result: %+v", res)
+	}
+	// short-circuit stops as soon as the quorum is reached
+	res = rbac.Query(GrantQuery{Roles: roles, Permission: NewDeepPermission("system"), Quorum: 2})
+	if !res.Granted || !equalStrings(res.GrantedBy, []string{"observer", "reporter"}) || len(res.DeniedBy) != 0 {
+		t.Fatalf("Unexpected result: %+v", res)
+	}
+	// or as soon as it can't be reached anymore
+	res = rbac.Query(GrantQuery{Roles: roles, Permission: NewDeepPermission("task:create"), Quorum: 4})
+	if res.Granted || !equalStrings(res.DeniedBy, []string{"admin"}) {
+		t.Fatalf("Unexpected result: %+v", res)
+	}
+}
+
+func TestRBAC_QueryAssertOnce(t *testing.T) {
+	rbac := prepareTree(t)
+	roles := []string{"observer", "reporter", "admin"}
+
+	var calls []string
+	assert := func(_ *RBAC, id string, _ Permission) bool {
+		calls = append(calls, id)
+		return id != "reporter"
+	}
+
+	res := rbac.Query(GrantQuery{Roles: roles, Permission: NewDeepPermission("system"), Assert: assert, Detailed: true})
+	if len(calls) != 3 || res.Granted || !equalStrings(res.DeniedBy, []string{"reporter"}) {
+		t.Fatalf("Unexpected result: %+v, calls: %v", res, calls)
+	}
+
+	calls = nil
+	res = rbac.Query(GrantQuery{Roles: roles, Permission: NewDeepPermission("system"), Assert: assert, AssertOnce: true})
+	if !equalStrings(calls, []string{""}) || !res.Granted || len(res.GrantedBy) != 3 {
+		t.Fatalf("Unexpected result: %+v, calls: %v", res, calls)
+	}
+
+	res = rbac.Query(GrantQuery{Roles: roles, Permission: NewDeepPermission("system"), AssertOnce: true,
+		Assert: func(*RBAC, string, Permission) bool { return false }})
+	if res.Granted || len(res.DeniedBy) != 3 {
+		t.Fatalf("Unexpected result: %+v", res)
+	}
+}
+
+func TestRBAC_QueryDecision(t *testing.T) {
+	rbac, list := prepareDecisions(t)
+
+	rbac.Query(GrantQuery{Roles: []string{"decision-1", "decision-2"}, Permission: NewDeepPermission("task:read"), Quorum: 2})
+	if d := (*list)[0]; d.Op != "Query" || !d.Granted || d.Role != "decision-1" || d.Matched != "task" {
+		t.Fatalf("Unexpected decision: %+v", d)
+	}
+	if d := (*list)[0]; d.Assertion != AssertionNone {
+		t.Fatalf("Unexpected assertion outcome: %+v", d)
+	}
+
+	roles := []string{"decision-1", "decision-2"}
+	notFirst := func(_ *RBAC, id string, _ Permission) bool { return id != "decision-1" }
+	rbac.Query(GrantQuery{Roles: roles, Permission: NewDeepPermission("task:read"), Assert: notFirst, Quorum: 1})
+	if d := (*list)[1]; !d.Granted || d.Role != "decision-1" || d.Assertion != AssertionPassed {
+		t.Fatalf("Unexpected decision: %+v", d)
+	}
+	rbac.Query(GrantQuery{Roles: roles, Permission: NewDeepPermission("user:read"), Assert: notFirst, Quorum: 2})
+	if d := (*list)[2]; d.Granted || d.Assertion != AssertionFailed {
+		t.Fatalf("Unexpected decision: %+v", d)
+	}
+	rbac.Query(GrantQuery{Roles: roles, Permission: NewDeepPermission("report"), Assert: notFirst, Quorum: 1})
+	if d := (*list)[3]; d.Granted || d.Assertion != AssertionFailed {
+		t.Fatalf("Unexpected decision: %+v", d)
+	}
+}
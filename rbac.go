@@ -18,40 +18,61 @@ type AssertionFunc func(*RBAC, string, Permission) bool
 
 // RBAC object, in most cases it should be used as a singleton.
 type RBAC struct {
-	mutex   sync.RWMutex
-	roles   Roles
-	parents map[string]map[string]struct{}
+	mutex    sync.RWMutex
+	roles    Roles
+	parents  map[string]map[string]struct{}
+	children map[string]map[string]struct{}
+	subjects map[string]map[string]struct{}
+	// members is the reverse index of subjects: role id to its subjects.
+	members  map[string]map[string]struct{}
+	logger   DecisionLogger
+	metrics  Metrics
+	handlers []ChangeHandler
+	// queue holds events not delivered yet, delivering is held while delivering them.
+	queue      []Event
+	delivering sync.Mutex
+	catalog    *Catalog
+	strict     bool
+	sorted     bool
+	maxDepth   int
+	depths     map[string]int
+	edges      map[edgeKey]edge
 }
 
-// New returns a RBAC structure.
+// New returns a RBAC structure configured by `opts`.
 // The default role structure will be used.
-func New() *RBAC {
-	return &RBAC{
-		roles:   make(Roles),
-		parents: make(map[string]map[string]struct{}),
+func New(opts ...Option) *RBAC {
+	rbac := &RBAC{
+		roles:    make(Roles),
+		parents:  make(map[string]map[string]struct{}),
+		children: make(map[string]map[string]struct{}),
+		subjects: make(map[string]map[string]struct{}),
+		members:  make(map[string]map[string]struct{}),
+		depths:   make(map[string]int),
+		edges:    make(map[edgeKey]edge),
 	}
+	for _, opt := range opts {
+		opt(rbac)
+	}
+	return rbac
 }
 
-// SetParents bind `parents` to the role `id`.
+// SetParents bind `parents` to the role `id` with options `opts`.
 // If the role or any of parents is not existing,
-// an error will be returned.
-func (rbac *RBAC) SetParents(id string, parents []string) error {
+// an error listing all missing roles will be returned.
+func (rbac *RBAC) SetParents(id string, parents []string, opts ...EdgeOption) error {
 	rbac.mutex.Lock()
-	defer rbac.mutex.Unlock()
-	if _, ok := rbac.roles[id]; !ok {
-		return ErrRoleNotExist
-	}
-	for _, parent := range parents {
-		if _, ok := rbac.roles[parent]; !ok {
-			return ErrRoleNotExist
-		}
-	}
-	if _, ok := rbac.parents[id]; !ok {
-		rbac.parents[id] = make(map[string]struct{})
+	if err := rbac.mustExist("SetParents", append([]string{id}, parents...)...); err != nil {
+		rbac.mutex.Unlock()
+		return err
 	}
+	events := make([]Event, 0, len(parents))
 	for _, parent := range parents {
-		rbac.parents[id][parent] = empty
+		events = append(events, edgeEvent(id, parent, rbac.setEdge(id, parent, opts)))
 	}
+	rbac.emit(events...)
+	rbac.mutex.Unlock()
+	rbac.notify()
 	return nil
 }
 
@@ -60,10 +81,10 @@ func (rbac *RBAC) SetParents(id string, parents []string) error {
 // Or the role doesn't have any parents,
 // a nil slice will be returned.
 func (rbac *RBAC) GetParents(id string) ([]string, error) {
-	rbac.mutex.Lock()
-	defer rbac.mutex.Unlock()
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
 	if _, ok := rbac.roles[id]; !ok {
-		return nil, ErrRoleNotExist
+		return nil, roleNotExist("GetParents", id)
 	}
 	ids, ok := rbac.parents[id]
 	if !ok {
@@ -73,26 +94,22 @@ func (rbac *RBAC) GetParents(id string) ([]string, error) {
 	for parent := range ids {
 		parents = append(parents, parent)
 	}
-	return parents, nil
+	return rbac.sortIDs(parents), nil
 }
 
-// SetParent bind the `parent` to the role `id`.
+// SetParent bind the `parent` to the role `id` with options `opts`,
+// binding an existing parent again replaces its options.
 // If the role or the parent is not existing,
 // an error will be returned.
-func (rbac *RBAC) SetParent(id string, parent string) error {
+func (rbac *RBAC) SetParent(id string, parent string, opts ...EdgeOption) error {
 	rbac.mutex.Lock()
-	defer rbac.mutex.Unlock()
-	if _, ok := rbac.roles[id]; !ok {
-		return ErrRoleNotExist
-	}
-	if _, ok := rbac.roles[parent]; !ok {
-		return ErrRoleNotExist
+	if err := rbac.mustExist("SetParent", id, parent); err != nil {
+		rbac.mutex.Unlock()
+		return err
 	}
-	if _, ok := rbac.parents[id]; !ok {
-		rbac.parents[id] = make(map[string]struct{})
-	}
-	var empty struct{}
-	rbac.parents[id][parent] = empty
+	rbac.emit(edgeEvent(id, parent, rbac.setEdge(id, parent, opts)))
+	rbac.mutex.Unlock()
+	rbac.notify()
 	return nil
 }
 
@@ -101,56 +118,110 @@ func (rbac *RBAC) SetParent(id string, parent string) error {
 // an error will be returned.
 func (rbac *RBAC) RemoveParent(id string, parent string) error {
 	rbac.mutex.Lock()
-	defer rbac.mutex.Unlock()
 
-	if _, ok := rbac.roles[id]; !ok {
-		return ErrRoleNotExist
-	}
-	if _, ok := rbac.roles[parent]; !ok {
-		return ErrRoleNotExist
+	if err := rbac.mustExist("RemoveParent", id, parent); err != nil {
+		rbac.mutex.Unlock()
+		return err
 	}
 
-	delete(rbac.parents[id], parent)
+	rbac.unbind(id, parent)
+	rbac.emit(Event{Op: EventRemoveParent, Role: id, Parent: parent})
+	rbac.mutex.Unlock()
+	rbac.notify()
+
+	return nil
+}
 
+// mustExist returns an error listing roles of `ids` which are not existing.
+// The lock has to be held by the caller.
+func (rbac *RBAC) mustExist(op string, ids ...string) error {
+	var missing []string
+	for _, id := range ids {
+		if _, ok := rbac.roles[id]; !ok {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) > 0 {
+		return roleNotExist(op, missing...)
+	}
 	return nil
 }
 
+// bind adds the edge between the role `id` and its `parent` into both indexes.
+func (rbac *RBAC) bind(id, parent string) {
+	if _, ok := rbac.parents[id]; !ok {
+		rbac.parents[id] = make(map[string]struct{})
+	}
+	rbac.parents[id][parent] = empty
+	if _, ok := rbac.children[parent]; !ok {
+		rbac.children[parent] = make(map[string]struct{})
+	}
+	rbac.children[parent][id] = empty
+}
+
+// unbind removes the edge between the role `id` and its `parent` from both indexes.
+func (rbac *RBAC) unbind(id, parent string) {
+	delete(rbac.edges, edgeKey{id, parent})
+	delete(rbac.parents[id], parent)
+	if len(rbac.parents[id]) == 0 {
+		delete(rbac.parents, id)
+	}
+	delete(rbac.children[parent], id)
+	if len(rbac.children[parent]) == 0 {
+		delete(rbac.children, parent)
+	}
+}
+
 // Add a role `r`.
 func (rbac *RBAC) Add(r Role) (err error) {
+	var events []Event
 	rbac.mutex.Lock()
-	if _, ok := rbac.roles[r.ID()]; !ok {
+	if _, ok := rbac.roles[r.ID()]; ok {
+		err = roleExist("Add", r.ID())
+	} else if err = rbac.declared(r.Permissions()...); err == nil {
 		rbac.roles[r.ID()] = r
-	} else {
-		err = ErrRoleExist
+		if rbac.watched() {
+			events = append(events, Event{Op: EventAddRole, Role: r.ID(), Permissions: permissionRecords(r.Permissions()), Metadata: roleMetadata(r)})
+		}
 	}
+	rbac.emit(events...)
 	rbac.mutex.Unlock()
+	rbac.notify()
 	return
 }
 
 // Remove the role by `id`.
 func (rbac *RBAC) Remove(id string) (err error) {
+	var events []Event
 	rbac.mutex.Lock()
 	if _, ok := rbac.roles[id]; ok {
-		delete(rbac.roles, id)
-		for rid, parents := range rbac.parents {
-			if rid == id {
-				delete(rbac.parents, rid)
-				continue
-			}
-			for parent := range parents {
-				if parent == id {
-					delete(rbac.parents[rid], id)
-					break
-				}
-			}
-		}
+		rbac.remove(id)
+		events = append(events, Event{Op: EventRemoveRole, Role: id})
 	} else {
-		err = ErrRoleNotExist
+		err = roleNotExist("Remove", id)
 	}
+	rbac.emit(events...)
 	rbac.mutex.Unlock()
+	rbac.notify()
 	return
 }
 
+// remove deletes the role `id` with its edges and subject assignments.
+// The lock has to be held by the caller.
+func (rbac *RBAC) remove(id string) {
+	delete(rbac.roles, id)
+	delete(rbac.depths, id)
+	for parent := range rbac.parents[id] {
+		rbac.unbind(id, parent)
+	}
+	for child := range rbac.children[id] {
+		rbac.unbind(child, id)
+	}
+	for subject := range rbac.members[id] {
+		rbac.unassignSubject(subject, id)
+	}
+}
+
 // GetRole by `id` and a slice of its parents id.
 func (rbac *RBAC) GetRole(id string) (r Role, parents []string, err error) {
 	rbac.mutex.RLock()
@@ -159,8 +230,9 @@ func (rbac *RBAC) GetRole(id string) (r Role, parents []string, err error) {
 		for parent := range rbac.parents[id] {
 			parents = append(parents, parent)
 		}
+		parents = rbac.sortIDs(parents)
 	} else {
-		err = ErrRoleNotExist
+		err = roleNotExist("GetRole", id)
 	}
 	rbac.mutex.RUnlock()
 	return
@@ -174,32 +246,23 @@ func (rbac *RBAC) GetRoles() []Role {
 	for _, r := range rbac.roles {
 		result = append(result, r)
 	}
+	if rbac.sorted {
+		SortRoles(result)
+	}
 
 	rbac.mutex.RUnlock()
 	return result
 }
 
-// Permissions get list of all permissions
+// Permissions get list of all permissions,
+// including inherited ones within inheritance limits and narrowed by edge filters.
 func (rbac *RBAC) Permissions(id string) Permissions {
 	rbac.mutex.RLock()
 	defer rbac.mutex.RUnlock()
 
 	list := make(Permissions)
-
-	if role, ok := rbac.roles[id]; ok {
-		for _, p := range role.Permissions() {
-			list[p.ID()] = p
-		}
-
-		if parents, ok := rbac.parents[id]; ok {
-			for pID := range parents {
-				if _, ok := rbac.roles[pID]; ok {
-					for _, p := range rbac.Permissions(pID) {
-						list[p.ID()] = p
-					}
-				}
-			}
-		}
+	for pid, o := range rbac.visible(id) {
+		list[pid] = o.p
 	}
 	return list
 }
@@ -207,8 +270,21 @@ func (rbac *RBAC) Permissions(id string) Permissions {
 // IsGranted tests if the role `id` has Permission `p` with the condition `assert`.
 func (rbac *RBAC) IsGranted(id string, p Permission, assert AssertionFunc) (rslt bool) {
 	rbac.mutex.RLock()
-	rslt = rbac.isGranted(id, p, assert)
+	rbac.observe(p)
+	t := rbac.timer()
+	if rbac.logger == nil {
+		rslt = rbac.isGranted(id, p, assert)
+		rbac.mutex.RUnlock()
+		t.done("IsGranted", p, rslt)
+		return
+	}
+	logger := rbac.logger
+	d := newDecision("IsGranted", []string{id}, p)
+	rslt = rbac.decide(id, p, assert, &d)
 	rbac.mutex.RUnlock()
+	t.done("IsGranted", p, rslt)
+	d.finish(rslt)
+	logger.LogDecision(d)
 	return
 }
 
@@ -216,23 +292,91 @@ func (rbac *RBAC) isGranted(id string, p Permission, assert AssertionFunc) bool
 	if assert != nil && !assert(rbac, id, p) {
 		return false
 	}
-	return rbac.recursionCheck(id, p)
+	_, ok := rbac.recursionCheck(id, p)
+	return ok
+}
+
+// decide works like isGranted, but records the assertion outcome
+// and the deciding role and permission into `d`.
+func (rbac *RBAC) decide(id string, p Permission, assert AssertionFunc, d *Decision) bool {
+	if assert != nil {
+		if !assert(rbac, id, p) {
+			d.Assertion = AssertionFailed
+			return false
+		}
+		d.Assertion = AssertionPassed
+	}
+	by, ok := rbac.recursionCheck(id, p)
+	if ok {
+		d.Role = by
+		d.Matched = matchedPermission(rbac.roles[by], p)
+	}
+	return ok
 }
 
-func (rbac *RBAC) recursionCheck(id string, p Permission) bool {
-	if role, ok := rbac.roles[id]; ok {
-		if role.Permit(p) {
-			return true
+// recursionCheck returns the id of the role which grants `p` to the role `id`.
+func (rbac *RBAC) recursionCheck(id string, p Permission) (string, bool) {
+	if p == nil {
+		return "", false
+	}
+	return rbac.check(id, p, rbac.depthLimit(), nil)
+}
+
+// check works like recursionCheck following at most `remaining` edges.
+// `visited` holds the largest number of remaining edges each role was entered with;
+// entering it again with no more edges can't grant anything new,
+// so cycles and diamonds are walked once.
+func (rbac *RBAC) check(id string, p Permission, remaining int, visited map[string]int) (string, bool) {
+	role, ok := rbac.roles[id]
+	if !ok {
+		return "", false
+	}
+	if n, ok := visited[id]; ok && n >= remaining {
+		return "", false
+	}
+	if role.Permit(p) {
+		return id, true
+	}
+	parents := rbac.parents[id]
+	if len(parents) == 0 {
+		return "", false
+	}
+	if visited == nil {
+		visited = make(map[string]int)
+	}
+	visited[id] = remaining
+	remaining = rbac.enter(id, remaining)
+	if remaining <= 0 {
+		return "", false
+	}
+	for pID := range parents {
+		if len(rbac.edges) > 0 && !rbac.edges[edgeKey{id, pID}].permits(p) {
+			continue
+		}
+		if by, ok := rbac.check(pID, p, rbac.cross(id, pID, remaining), visited); ok {
+			return by, true
 		}
-		if parents, ok := rbac.parents[id]; ok {
-			for pID := range parents {
-				if _, ok := rbac.roles[pID]; ok {
-					if rbac.recursionCheck(pID, p) {
-						return true
-					}
-				}
-			}
+	}
+	return "", false
+}
+
+// matchedPermission returns the id of the role's permission which matches `p`.
+func matchedPermission(role Role, p Permission) string {
+	if role == nil || p == nil {
+		return ""
+	}
+	for _, rp := range role.Permissions() {
+		if rp.Match(p) {
+			return rp.ID()
 		}
 	}
-	return false
+	return ""
+}
+
+// SetDecisionLogger registers `l` to receive a record of every authorization decision
+// made by IsGranted, AnyGranted, AllGranted, CheckMany and Query. A nil `l` disables logging.
+func (rbac *RBAC) SetDecisionLogger(l DecisionLogger) {
+	rbac.mutex.Lock()
+	rbac.logger = l
+	rbac.mutex.Unlock()
 }
@@ -1,6 +1,7 @@
 package gorbac
 
 import (
+	"errors"
 	"fmt"
 	"strconv"
 	"testing"
@@ -30,7 +31,7 @@ func init() {
 func TestRBAC_Add(t *testing.T) {
 	rbac := New()
 	assert(t, rbac.Add(rA))
-	if err := rbac.Add(rA); err != ErrRoleExist {
+	if err := rbac.Add(rA); !errors.Is(err, ErrRoleExist) {
 		t.Error("A role can not be added")
 	}
 	assert(t, rbac.Add(rB))
@@ -70,7 +71,7 @@ func TestRBAC_GetRole(t *testing.T) {
 
 	role, parents, err = rbac.GetRole(`role-d`)
 
-	if err != ErrRoleNotExist {
+	if !errors.Is(err, ErrRoleNotExist) {
 		t.Fatal(`Here have to be an Error`)
 	}
 
@@ -104,11 +105,11 @@ func TestRBAC_GetRemove(t *testing.T) {
 	if _, ok := rbac.roles["role-a"]; ok {
 		t.Fatal("Role removing failed")
 	}
-	if err := rbac.Remove("not-exist"); err != ErrRoleNotExist {
+	if err := rbac.Remove("not-exist"); !errors.Is(err, ErrRoleNotExist) {
 		t.Fatalf("%s needed", ErrRoleNotExist)
 	}
 
-	if r, parents, err := rbac.GetRole("role-a"); err != ErrRoleNotExist {
+	if r, parents, err := rbac.GetRole("role-a"); !errors.Is(err, ErrRoleNotExist) {
 		t.Fatalf("%s needed", ErrRoleNotExist)
 	} else if r != nil {
 		t.Fatal("The instance of role should be a nil")
@@ -133,23 +134,23 @@ func TestRbacParents(t *testing.T) {
 		t.Fatal("Parent unbinding failed")
 	}
 
-	if err := rbac.RemoveParent("role-a", "role-b"); err != ErrRoleNotExist {
+	if err := rbac.RemoveParent("role-a", "role-b"); !errors.Is(err, ErrRoleNotExist) {
 		t.Fatalf("%s needed", ErrRoleNotExist)
 	}
-	if err := rbac.RemoveParent("role-b", "role-a"); err != ErrRoleNotExist {
+	if err := rbac.RemoveParent("role-b", "role-a"); !errors.Is(err, ErrRoleNotExist) {
 		t.Fatalf("%s needed", ErrRoleNotExist)
 	}
 
-	if err := rbac.SetParent("role-a", "role-b"); err != ErrRoleNotExist {
+	if err := rbac.SetParent("role-a", "role-b"); !errors.Is(err, ErrRoleNotExist) {
 		t.Fatalf("%s needed", ErrRoleNotExist)
 	}
-	if err := rbac.SetParent("role-c", "role-a"); err != ErrRoleNotExist {
+	if err := rbac.SetParent("role-c", "role-a"); !errors.Is(err, ErrRoleNotExist) {
 		t.Fatalf("%s needed", ErrRoleNotExist)
 	}
-	if err := rbac.SetParents("role-a", []string{"role-b"}); err != ErrRoleNotExist {
+	if err := rbac.SetParents("role-a", []string{"role-b"}); !errors.Is(err, ErrRoleNotExist) {
 		t.Fatalf("%s needed", ErrRoleNotExist)
 	}
-	if err := rbac.SetParents("role-c", []string{"role-a", "role-sa"}); err != ErrRoleNotExist {
+	if err := rbac.SetParents("role-c", []string{"role-a", "role-sa"}); !errors.Is(err, ErrRoleNotExist) {
 		t.Fatalf("%s needed", ErrRoleNotExist)
 	}
 
@@ -157,7 +158,7 @@ func TestRbacParents(t *testing.T) {
 	if _, ok := rbac.parents["role-c"]["role-b"]; !ok {
 		t.Fatal("Parent binding failed")
 	}
-	if parents, err := rbac.GetParents("role-a"); err != ErrRoleNotExist {
+	if parents, err := rbac.GetParents("role-a"); !errors.Is(err, ErrRoleNotExist) {
 		t.Fatalf("%s needed", ErrRoleNotExist)
 	} else if len(parents) != 0 {
 		t.Fatal("[role-a] should not have any parent")
@@ -174,6 +175,41 @@ func TestRbacParents(t *testing.T) {
 	}
 }
 
+func TestRoleError(t *testing.T) {
+	rbac := New()
+	assert(t, rbac.Add(rA))
+
+	err := rbac.SetParents("role-a", []string{"role-b", "role-a", "role-c"})
+	var re *RoleError
+	if !errors.As(err, &re) {
+		t.Fatalf("RoleError needed, but %v got", err)
+	}
+	if re.Op != "SetParents" || len(re.IDs) != 2 || re.IDs[0] != "role-b" || re.IDs[1] != "role-c" {
+		t.Fatalf("Unexpected error: %+v", re)
+	}
+	if err.Error() != "SetParents role-b, role-c: role does not exist" {
+		t.Fatalf("Unexpected message: %s", err)
+	}
+
+	for op, err := range map[string]error{
+		"SetParent":    rbac.SetParent("role-a", "role-b"),
+		"RemoveParent": rbac.RemoveParent("role-b", "role-a"),
+		"Remove":       rbac.Remove("role-b"),
+	} {
+		if !errors.As(err, &re) || re.Op != op || len(re.IDs) != 1 || re.IDs[0] != "role-b" {
+			t.Fatalf("%s: unexpected error %v", op, err)
+		}
+	}
+	if _, err := rbac.GetParents("role-b"); !errors.As(err, &re) || re.Op != "GetParents" {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	err = rbac.Add(rA)
+	if !errors.As(err, &re) || re.Op != "Add" || re.IDs[0] != "role-a" || !errors.Is(err, ErrRoleExist) {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+}
+
 func TestRbacPermission(t *testing.T) {
 	rbac := New()
 
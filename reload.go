@@ -0,0 +1,134 @@
+package gorbac
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// ReadPolicy decodes a JSON policy from `r`.
+func ReadPolicy(r io.Reader) (*Policy, error) {
+	var policy Policy
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&policy); err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// Replace atomically swaps roles, parents, inheritance limits and subjects of `rbac` by the ones of `other`.
+// The decision logger, change handlers and options of `rbac` are kept,
+// no change events are emitted. `other` must not be used afterwards.
+// In strict mode, if any permission of `other` is undeclared, an error will be returned
+// and `rbac` is left unchanged.
+func (rbac *RBAC) Replace(other *RBAC) error {
+	other.mutex.Lock()
+	roles, parents, children, subjects, members := other.roles, other.parents, other.children, other.subjects, other.members
+	depths, edges := other.depths, other.edges
+	other.mutex.Unlock()
+
+	rbac.mutex.Lock()
+	defer rbac.mutex.Unlock()
+	if err := rbac.declaredRoles(roles); err != nil {
+		return err
+	}
+	rbac.roles, rbac.parents, rbac.children, rbac.subjects, rbac.members = roles, parents, children, subjects, members
+	rbac.depths, rbac.edges = depths, edges
+	return nil
+}
+
+// Reloader keeps `rbac` in sync with a JSON policy file.
+// A new policy is applied only if it is valid and has no circle inheritance,
+// otherwise the error is reported and the previous policy stays active.
+type Reloader struct {
+	rbac    *RBAC
+	path    string
+	onError func(error)
+
+	mutex   sync.Mutex
+	modTime time.Time
+	size    int64
+	content []byte
+}
+
+// NewReloader returns a Reloader of `rbac` from the file `path`.
+// `onError` is called with every failed reload, it may be nil.
+func NewReloader(rbac *RBAC, path string, onError func(error)) *Reloader {
+	return &Reloader{rbac: rbac, path: path, onError: onError}
+}
+
+// Reload reads, validates and applies the policy file if its content changed.
+// It returns true if the policy was swapped.
+func (r *Reloader) Reload() (bool, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return false, r.fail(err)
+	}
+	if r.content != nil && info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return false, nil
+	}
+	content, err := os.ReadFile(r.path)
+	if err != nil {
+		return false, r.fail(err)
+	}
+	r.modTime, r.size = info.ModTime(), info.Size()
+	if r.content != nil && bytes.Equal(content, r.content) {
+		return false, nil
+	}
+
+	next, err := r.load(content)
+	if err != nil {
+		return false, r.fail(err)
+	}
+	if err := r.rbac.Replace(next); err != nil {
+		return false, r.fail(err)
+	}
+	r.content = content
+	return true, nil
+}
+
+func (r *Reloader) load(content []byte) (*RBAC, error) {
+	policy, err := ReadPolicy(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	next, err := NewFromPolicy(policy)
+	if err != nil {
+		return nil, err
+	}
+	if err := InheritanceCircle(next); err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+func (r *Reloader) fail(err error) error {
+	if r.onError != nil {
+		r.onError(err)
+	}
+	return err
+}
+
+// Run polls the file every `interval` until `ctx` is done.
+// The file is loaded immediately.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	r.Reload()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.Reload()
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package gorbac
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const reloadPolicy = `{"roles": [
+	{"id": "user", "permissions": [{"id": "profile", "kind": "deep", "sep": ":"}]},
+	{"id": "admin", "permissions": [{"id": "user", "kind": "deep", "sep": ":"}], "parents": ["user"]}
+]}`
+
+func writePolicy(t *testing.T, path, content string, mod time.Time) {
+	assert(t, os.WriteFile(path, []byte(content), 0600))
+	assert(t, os.Chtimes(path, mod, mod))
+}
+
+func TestReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	mod := time.Now().Add(-time.Hour)
+	writePolicy(t, path, reloadPolicy, mod)
+
+	rbac := New()
+	var errs []error
+	r := NewReloader(rbac, path, func(err error) { errs = append(errs, err) })
+
+	if ok, err := r.Reload(); err != nil || !ok {
+		t.Fatalf("Policy should be loaded: %v", err)
+	}
+	if !rbac.IsGranted("admin", NewDeepPermission("profile:update"), nil) {
+		t.Fatal("[admin] should inherit the profile permission")
+	}
+	if ok, err := r.Reload(); err != nil || ok {
+		t.Fatal("Unchanged file should not be reloaded")
+	}
+
+	// a circle inheritance keeps the previous policy
+	mod = mod.Add(time.Minute)
+	writePolicy(t, path, strings.Replace(reloadPolicy,
+		`"permissions": [{"id": "profile", "kind": "deep", "sep": ":"}]`,
+		`"permissions": [{"id": "profile", "kind": "deep", "sep": ":"}], "parents": ["admin"]`, 1), mod)
+	if ok, err := r.Reload(); err != ErrFoundCircle || ok {
+		t.Fatalf("%s needed, but %v got", ErrFoundCircle, err)
+	}
+
+	// broken syntax keeps the previous policy
+	mod = mod.Add(time.Minute)
+	writePolicy(t, path, `{"roles": [`, mod)
+	if _, err := r.Reload(); err == nil {
+		t.Fatal("Broken policy should fail")
+	}
+	if len(errs) != 2 {
+		t.Fatalf("2 reported errors expected, but %d got", len(errs))
+	}
+	if !rbac.IsGranted("admin", NewDeepPermission("user:delete"), nil) {
+		t.Fatal("Previous policy should stay active")
+	}
+
+	mod = mod.Add(time.Minute)
+	writePolicy(t, path, `{"roles": [{"id": "guest"}]}`, mod)
+	if ok, err := r.Reload(); err != nil || !ok {
+		t.Fatalf("Policy should be reloaded: %v", err)
+	}
+	if _, _, err := rbac.GetRole("admin"); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatal("Policy should be swapped")
+	}
+	if _, _, err := rbac.GetRole("guest"); err != nil {
+		t.Fatal(err)
+	}
+}
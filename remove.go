@@ -0,0 +1,156 @@
+package gorbac
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrRoleHasChildren occurred if a role can't be removed because other roles inherit from it
+var ErrRoleHasChildren = errors.New("role has children")
+
+// RemoveStrategy defines what happens to children of a removed role.
+type RemoveStrategy int
+
+const (
+	// RemoveRefuse refuses to remove a role having children.
+	RemoveRefuse RemoveStrategy = iota
+	// RemoveReparent binds children to the parents of the removed role,
+	// so they keep the permissions inherited through it.
+	// Inheritance limits and filters along the removed role are combined into the new edges;
+	// if filters can't be combined, ErrEdgesIncompatible is returned.
+	RemoveReparent
+	// RemoveCascade removes the role with all its descendants.
+	RemoveCascade
+)
+
+// RemovalReport describes the outcome of RemoveWith.
+type RemovalReport struct {
+	// Removed are the removed roles, sorted.
+	Removed []string
+	// Affected are the remaining roles whose effective permissions changed, sorted.
+	Affected []string
+	// Lost maps affected roles to the sorted ids of permissions they don't have anymore.
+	Lost map[string][]string
+	// Gained maps affected roles to the sorted ids of permissions they didn't have before.
+	Gained map[string][]string
+}
+
+// RemoveWith removes the role `id` handling its children by `strategy`.
+// If the role is not existing, it has children and the strategy is RemoveRefuse,
+// or edges along it can't be combined by RemoveReparent, an error will be returned.
+func (rbac *RBAC) RemoveWith(id string, strategy RemoveStrategy) (*RemovalReport, error) {
+	rbac.mutex.Lock()
+	if _, ok := rbac.roles[id]; !ok {
+		rbac.mutex.Unlock()
+		return nil, roleNotExist("RemoveWith", id)
+	}
+
+	descendants := rbac.reach(id, rbac.children)
+	if strategy == RemoveRefuse {
+		var children []string
+		for child := range rbac.children[id] {
+			if child != id {
+				children = append(children, child)
+			}
+		}
+		if len(children) > 0 {
+			sort.Strings(children)
+			rbac.mutex.Unlock()
+			return nil, &RoleError{Op: "RemoveWith", IDs: children, Err: ErrRoleHasChildren}
+		}
+	}
+
+	before := make(map[string]map[string]struct{}, len(descendants))
+	for _, d := range descendants {
+		before[d] = rbac.effectiveIDs(d)
+	}
+
+	type link struct {
+		child, parent string
+		e             edge
+	}
+	var links []link
+	if strategy == RemoveReparent {
+		for _, child := range sortedKeys(rbac.children[id]) {
+			for _, parent := range sortedKeys(rbac.parents[id]) {
+				if _, ok := rbac.parents[child][parent]; ok || parent == child || child == id || parent == id {
+					continue
+				}
+				e, ok, err := chain(rbac.edges[edgeKey{child, id}], rbac.depths[id], rbac.edges[edgeKey{id, parent}])
+				if err != nil {
+					rbac.mutex.Unlock()
+					return nil, &RoleError{Op: "RemoveWith", IDs: []string{child, parent}, Err: err}
+				}
+				if ok {
+					links = append(links, link{child, parent, e})
+				}
+			}
+		}
+	}
+
+	report := &RemovalReport{Removed: []string{id}, Lost: make(map[string][]string), Gained: make(map[string][]string)}
+	var events []Event
+	switch strategy {
+	case RemoveReparent:
+		for _, l := range links {
+			events = append(events, edgeEvent(l.child, l.parent, rbac.setEdge(l.child, l.parent, l.e.options())))
+		}
+	case RemoveCascade:
+		for _, d := range descendants {
+			if d == id {
+				continue
+			}
+			rbac.remove(d)
+			report.Removed = append(report.Removed, d)
+			events = append(events, Event{Op: EventRemoveRole, Role: d})
+		}
+	}
+	rbac.remove(id)
+	events = append([]Event{{Op: EventRemoveRole, Role: id}}, events...)
+
+	for d, ids := range before {
+		if _, ok := rbac.roles[d]; !ok {
+			continue
+		}
+		after := rbac.effectiveIDs(d)
+		var lost, gained []string
+		for pid := range ids {
+			if _, ok := after[pid]; !ok {
+				lost = append(lost, pid)
+			}
+		}
+		for pid := range after {
+			if _, ok := ids[pid]; !ok {
+				gained = append(gained, pid)
+			}
+		}
+		if len(lost) > 0 {
+			sort.Strings(lost)
+			report.Lost[d] = lost
+		}
+		if len(gained) > 0 {
+			sort.Strings(gained)
+			report.Gained[d] = gained
+		}
+		if len(lost) > 0 || len(gained) > 0 {
+			report.Affected = append(report.Affected, d)
+		}
+	}
+	rbac.emit(events...)
+	rbac.mutex.Unlock()
+
+	sort.Strings(report.Removed)
+	sort.Strings(report.Affected)
+	rbac.notify()
+	return report, nil
+}
+
+// effectiveIDs returns ids of permissions of the role `id` including inherited ones.
+// The lock has to be held by the caller.
+func (rbac *RBAC) effectiveIDs(id string) map[string]struct{} {
+	ids := make(map[string]struct{})
+	for pid := range rbac.visible(id) {
+		ids[pid] = empty
+	}
+	return ids
+}
@@ -0,0 +1,157 @@
+package gorbac
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRBAC_RemoveWithRefuse(t *testing.T) {
+	rbac := prepareTree(t)
+
+	_, err := rbac.RemoveWith("admin", RemoveRefuse)
+	var re *RoleError
+	if !errors.Is(err, ErrRoleHasChildren) || !errors.As(err, &re) || !equalStrings(re.IDs, []string{"moderator"}) {
+		t.Fatalf("%s needed, but %v got", ErrRoleHasChildren, err)
+	}
+	if _, _, err := rbac.GetRole("admin"); err != nil {
+		t.Fatal("Refused role should be kept")
+	}
+
+	report, err := rbac.RemoveWith("observer", RemoveRefuse)
+	assert(t, err)
+	if !equalStrings(report.Removed, []string{"observer"}) || len(report.Affected) != 0 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+
+	assert(t, rbac.Add(NewRole("self")))
+	assert(t, rbac.SetParent("self", "self"))
+	if _, err := rbac.RemoveWith("self", RemoveRefuse); err != nil {
+		t.Fatalf("A role being its own parent should be removed: %v", err)
+	}
+
+	if _, err := rbac.RemoveWith("not-exist", RemoveCascade); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatalf("%s needed", ErrRoleNotExist)
+	}
+}
+
+func TestRBAC_RemoveWithReparent(t *testing.T) {
+	rbac := prepareTree(t)
+	var events []Event
+	rbac.OnChange(func(e Event) {
+		events = append(events, e)
+	})
+
+	report, err := rbac.RemoveWith("moderator", RemoveReparent)
+	assert(t, err)
+	if !equalStrings(report.Removed, []string{"moderator"}) ||
+		!equalStrings(report.Affected, []string{"observer", "reporter"}) ||
+		!equalStrings(report.Lost["observer"], []string{"task"}) {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if parents, _ := rbac.GetParents("observer"); !equalStrings(parents, []string{"admin"}) {
+		t.Fatalf("Unexpected parents: %v", parents)
+	}
+	if !rbac.IsGranted("reporter", NewDeepPermission("system"), nil) {
+		t.Fatal("Reparented role should keep inherited permissions")
+	}
+
+	if len(events) != 3 || events[0].Op != EventRemoveRole || events[1].Op != EventSetParent {
+		t.Fatalf("Unexpected events: %+v", events)
+	}
+	replica := prepareTree(t)
+	assert(t, replica.Apply(events...))
+	expected, err := rbac.Export()
+	assert(t, err)
+	got, err := replica.Export()
+	assert(t, err)
+	if !equalPolicies(expected, got) {
+		t.Fatalf("Replayed policy differs:\n%+v\n%+v", expected, got)
+	}
+}
+
+func TestRBAC_RemoveWithCascade(t *testing.T) {
+	rbac := prepareTree(t)
+	assert(t, rbac.AssignSubject("alice", "reporter"))
+
+	report, err := rbac.RemoveWith("admin", RemoveCascade)
+	assert(t, err)
+	if !equalStrings(report.Removed, []string{"admin", "moderator", "observer", "reporter"}) || len(report.Affected) != 0 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if roles := rbac.GetRoles(); len(roles) != 1 || roles[0].ID() != "root" {
+		t.Fatalf("Only root should be kept: %v", roles)
+	}
+	if roles := rbac.SubjectRoles("alice"); len(roles) != 0 {
+		t.Fatalf("Removed role should be unassigned: %v", roles)
+	}
+}
+
+func TestRBAC_RemoveWithReparentOptions(t *testing.T) {
+	rbac := New()
+	assert(t, rbac.Add(NewRole("billing").Assign(NewDeepPermission("invoice:write"))))
+	assert(t, rbac.Add(NewRole("dept").Assign(NewDeepPermission("report:dept"))))
+	assert(t, rbac.Add(NewRole("auditor")))
+	assert(t, rbac.SetParent("dept", "billing"))
+	assert(t, rbac.SetParent("auditor", "dept", InheritOnly(NewDeepPermission("report"))))
+	var events []Event
+	rbac.OnChange(func(e Event) {
+		events = append(events, e)
+	})
+
+	report, err := rbac.RemoveWith("dept", RemoveReparent)
+	assert(t, err)
+	if rbac.IsGranted("auditor", NewDeepPermission("invoice:write"), nil) {
+		t.Fatal("Filters should be kept by reparenting")
+	}
+	if !equalStrings(report.Affected, []string{"auditor"}) || !equalStrings(report.Lost["auditor"], []string{"report:dept"}) || len(report.Gained) != 0 {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+	if len(events) != 2 || len(events[1].Permissions) != 1 {
+		t.Fatalf("Unexpected events: %+v", events)
+	}
+
+	rbac = New()
+	for _, id := range []string{"a", "b", "c", "d"} {
+		assert(t, rbac.Add(NewRole(id).Assign(NewPermission(id))))
+	}
+	assert(t, rbac.SetParent("a", "b", InheritDepth(2)))
+	assert(t, rbac.SetParent("b", "c"))
+	assert(t, rbac.SetParent("c", "d"))
+	_, err = rbac.RemoveWith("b", RemoveReparent)
+	assert(t, err)
+	if !rbac.IsGranted("a", NewPermission("c"), nil) || rbac.IsGranted("a", NewPermission("d"), nil) {
+		t.Fatal("Depth limits should be kept by reparenting")
+	}
+	if depth := rbac.edges[edgeKey{"a", "c"}].depth; depth != 1 {
+		t.Fatalf("Unexpected depth: %d", depth)
+	}
+
+	rbac = New()
+	for _, id := range []string{"a", "b", "c"} {
+		assert(t, rbac.Add(NewRole(id)))
+	}
+	assert(t, rbac.SetParent("a", "b", InheritOnly(NewDeepPermission("report"))))
+	assert(t, rbac.SetParent("b", "c", InheritOnly(NewResourcePermission("report", "read"))))
+	if _, err := rbac.RemoveWith("b", RemoveReparent); !errors.Is(err, ErrEdgesIncompatible) {
+		t.Fatalf("%s needed, but %v got", ErrEdgesIncompatible, err)
+	}
+	if _, _, err := rbac.GetRole("b"); err != nil {
+		t.Fatal("Refused role should be kept")
+	}
+}
+
+func TestRBAC_RemoveWithGained(t *testing.T) {
+	rbac := New()
+	for _, id := range []string{"a", "b", "c"} {
+		assert(t, rbac.Add(NewRole(id).Assign(NewPermission(id))))
+	}
+	assert(t, rbac.SetParent("a", "b"))
+	assert(t, rbac.SetParent("b", "c"))
+	assert(t, rbac.SetInheritDepth("a", 1))
+
+	report, err := rbac.RemoveWith("b", RemoveReparent)
+	assert(t, err)
+	if !equalStrings(report.Lost["a"], []string{"b"}) || !equalStrings(report.Gained["a"], []string{"c"}) {
+		t.Fatalf("Unexpected report: %+v", report)
+	}
+}
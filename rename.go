@@ -0,0 +1,138 @@
+package gorbac
+
+import "sort"
+
+// Rename changes the identity of the role `old` to `id`,
+// rewriting all its parent edges, inheritance limits and subject assignments atomically.
+// The renamed role is a SimpleRole holding the same permissions and metadata.
+// If `old` is not existing or `id` has already existed, an error will be returned.
+// Change handlers receive the rename as adding `id`, binding its edges and subjects
+// and removing `old`, so replicas and storages replay it without special support.
+func (rbac *RBAC) Rename(old, id string) error {
+	rbac.mutex.Lock()
+	if err := rbac.mustExist("Rename", old); err != nil {
+		rbac.mutex.Unlock()
+		return err
+	}
+	if _, ok := rbac.roles[id]; ok {
+		rbac.mutex.Unlock()
+		return roleExist("Rename", id)
+	}
+
+	role := NewRole(id)
+	for _, p := range rbac.roles[old].Permissions() {
+		role.Assign(p)
+	}
+	role.SetMetadata(MetadataOf(rbac.roles[old]))
+	parents := sortedKeys(rbac.parents[old])
+	children := sortedKeys(rbac.children[old])
+	subjects := rbac.subjectsOf(old)
+
+	rbac.roles[id] = role
+	events := []Event{{Op: EventAddRole, Role: id, Permissions: permissionRecords(role.Permissions()), Metadata: roleMetadata(role)}}
+	if depth, ok := rbac.depths[old]; ok {
+		rbac.depths[id] = depth
+		events = append(events, Event{Op: EventSetDepth, Role: id, Depth: depth})
+	}
+	for _, parent := range parents {
+		opts := rbac.edgeOptions(old, parent)
+		if parent == old {
+			parent = id
+		}
+		events = append(events, edgeEvent(id, parent, rbac.setEdge(id, parent, opts)))
+	}
+	for _, child := range children {
+		if child == old {
+			continue
+		}
+		events = append(events, edgeEvent(child, id, rbac.setEdge(child, id, rbac.edgeOptions(child, old))))
+	}
+	for _, subject := range subjects {
+		rbac.assignSubject(subject, id)
+		events = append(events, Event{Op: EventAssignSubject, Role: id, Subject: subject})
+	}
+	rbac.remove(old)
+	events = append(events, Event{Op: EventRemoveRole, Role: old})
+	rbac.emit(events...)
+	rbac.mutex.Unlock()
+	rbac.notify()
+	return nil
+}
+
+// Merge unions permissions, parents, children and subjects of the role `src`
+// into the role `dst`, then removes `src`.
+// The edge between both roles is dropped rather than turned into a self-loop.
+// If any of roles is not existing, an error will be returned;
+// `dst` has to implement Assign and Revoke as SimpleRole does.
+func (rbac *RBAC) Merge(src, dst string) error {
+	if src == dst {
+		return roleExist("Merge", dst)
+	}
+	rbac.mutex.Lock()
+	if err := rbac.mustExist("Merge", src, dst); err != nil {
+		rbac.mutex.Unlock()
+		return err
+	}
+	mr, ok := rbac.roles[dst].(mutableRole)
+	if !ok {
+		rbac.mutex.Unlock()
+		return ErrRoleImmutable
+	}
+
+	if err := rbac.declared(rbac.roles[src].Permissions()...); err != nil {
+		rbac.mutex.Unlock()
+		return err
+	}
+	own := make(map[string]struct{})
+	for _, p := range rbac.roles[dst].Permissions() {
+		own[p.ID()] = empty
+	}
+	var events []Event
+	for _, p := range rbac.roles[src].Permissions() {
+		if _, ok := own[p.ID()]; ok {
+			continue
+		}
+		mr.Assign(p)
+		events = append(events, Event{Op: EventAssign, Role: dst, Permissions: permissionRecords([]Permission{p})})
+	}
+	for _, parent := range sortedKeys(rbac.parents[src]) {
+		if _, ok := rbac.parents[dst][parent]; ok || parent == dst || parent == src {
+			continue
+		}
+		events = append(events, edgeEvent(dst, parent, rbac.setEdge(dst, parent, rbac.edgeOptions(src, parent))))
+	}
+	for _, child := range sortedKeys(rbac.children[src]) {
+		if _, ok := rbac.parents[child][dst]; ok || child == dst || child == src {
+			continue
+		}
+		events = append(events, edgeEvent(child, dst, rbac.setEdge(child, dst, rbac.edgeOptions(child, src))))
+	}
+	for _, subject := range rbac.subjectsOf(src) {
+		if _, ok := rbac.subjects[subject][dst]; ok {
+			continue
+		}
+		rbac.assignSubject(subject, dst)
+		events = append(events, Event{Op: EventAssignSubject, Role: dst, Subject: subject})
+	}
+	rbac.remove(src)
+	events = append(events, Event{Op: EventRemoveRole, Role: src})
+	rbac.emit(events...)
+	rbac.mutex.Unlock()
+	rbac.notify()
+	return nil
+}
+
+// subjectsOf returns the sorted subjects the role `id` is assigned to.
+// The lock has to be held by the caller.
+func (rbac *RBAC) subjectsOf(id string) []string {
+	return sortedKeys(rbac.members[id])
+}
+
+func sortedKeys(m map[string]struct{}) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
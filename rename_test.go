@@ -0,0 +1,89 @@
+package gorbac
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRBAC_Rename(t *testing.T) {
+	rbac := prepareTree(t)
+	assert(t, rbac.AssignSubject("alice", "reporter"))
+	var events []Event
+	rbac.OnChange(func(e Event) {
+		events = append(events, e)
+	})
+
+	assert(t, rbac.Rename("moderator", "editor"))
+	if _, _, err := rbac.GetRole("moderator"); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatal("Old role should be removed")
+	}
+	if parents, _ := rbac.GetParents("editor"); !equalStrings(parents, []string{"admin"}) {
+		t.Fatalf("Unexpected parents: %v", parents)
+	}
+	if children, _ := rbac.Children("editor"); !equalStrings(children, []string{"observer", "reporter"}) {
+		t.Fatalf("Unexpected children: %v", children)
+	}
+	if !rbac.IsGranted("observer", NewDeepPermission("task:delete"), nil) {
+		t.Fatal("Renamed role should keep its permissions")
+	}
+
+	assert(t, rbac.Rename("reporter", "contributor"))
+	if roles := rbac.SubjectRoles("alice"); !equalStrings(roles, []string{"contributor"}) {
+		t.Fatalf("Subjects should be reassigned: %v", roles)
+	}
+
+	replica := prepareTree(t)
+	assert(t, replica.AssignSubject("alice", "reporter"))
+	assert(t, replica.Apply(events...))
+	expected, err := rbac.Export()
+	assert(t, err)
+	got, err := replica.Export()
+	assert(t, err)
+	if !equalPolicies(expected, got) {
+		t.Fatalf("Replayed policy differs:\n%+v\n%+v", expected, got)
+	}
+
+	if err := rbac.Rename("admin", "root"); !errors.Is(err, ErrRoleExist) {
+		t.Fatalf("%s needed", ErrRoleExist)
+	}
+	if err := rbac.Rename("not-exist", "any"); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatalf("%s needed", ErrRoleNotExist)
+	}
+}
+
+func TestRBAC_Merge(t *testing.T) {
+	rbac := prepareTree(t)
+	assert(t, rbac.AssignSubject("alice", "observer"))
+	assert(t, rbac.AssignSubject("bob", "reporter"))
+	var events []Event
+	rbac.OnChange(func(e Event) {
+		events = append(events, e)
+	})
+
+	assert(t, rbac.Merge("observer", "reporter"))
+	if _, _, err := rbac.GetRole("observer"); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatal("Merged role should be removed")
+	}
+	if roles := rbac.SubjectRoles("alice"); !equalStrings(roles, []string{"reporter"}) {
+		t.Fatalf("Subjects should be reassigned: %v", roles)
+	}
+	// observer's only permission and parent are already held by reporter
+	if len(events) != 2 || events[0].Op != EventAssignSubject || events[1].Op != EventRemoveRole {
+		t.Fatalf("Unexpected events: %+v", events)
+	}
+
+	assert(t, rbac.Merge("moderator", "reporter"))
+	if parents, _ := rbac.GetParents("reporter"); !equalStrings(parents, []string{"admin"}) {
+		t.Fatalf("Unexpected parents: %v", parents)
+	}
+	if !rbac.IsGranted("reporter", NewDeepPermission("task:delete"), nil) {
+		t.Fatal("Permissions should be merged")
+	}
+
+	if err := rbac.Merge("reporter", "reporter"); !errors.Is(err, ErrRoleExist) {
+		t.Fatalf("%s needed", ErrRoleExist)
+	}
+	if err := rbac.Merge("not-exist", "reporter"); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatalf("%s needed", ErrRoleNotExist)
+	}
+}
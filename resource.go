@@ -0,0 +1,189 @@
+package gorbac
+
+import (
+	"strings"
+	"sync"
+)
+
+// AnyAction grants every action on a resource.
+const AnyAction = "*"
+
+// ActionHierarchy describes which actions imply other actions,
+// e.g. a role allowed to `write` a resource is allowed to `read` it.
+type ActionHierarchy struct {
+	mutex   sync.RWMutex
+	implies map[string]map[string]struct{}
+}
+
+// NewActionHierarchy returns an empty hierarchy: every action implies only itself.
+func NewActionHierarchy() *ActionHierarchy {
+	return &ActionHierarchy{implies: make(map[string]map[string]struct{})}
+}
+
+// DefaultActions is used by resource permissions without own hierarchy.
+var DefaultActions = NewActionHierarchy().Imply("write", "read")
+
+// Imply declares that `action` implies every action of `implied`.
+func (h *ActionHierarchy) Imply(action string, implied ...string) *ActionHierarchy {
+	h.mutex.Lock()
+	if _, ok := h.implies[action]; !ok {
+		h.implies[action] = make(map[string]struct{})
+	}
+	for _, a := range implied {
+		h.implies[action][a] = empty
+		if _, ok := h.implies[a]; !ok {
+			h.implies[a] = make(map[string]struct{})
+		}
+	}
+	h.mutex.Unlock()
+	return h
+}
+
+// Has returns true if the action is declared in the hierarchy.
+func (h *ActionHierarchy) Has(action string) bool {
+	if action == AnyAction {
+		return true
+	}
+	h.mutex.RLock()
+	_, ok := h.implies[action]
+	h.mutex.RUnlock()
+	return ok
+}
+
+// Implies returns true if `action` is `target`, AnyAction,
+// or implies `target` directly or transitively.
+func (h *ActionHierarchy) Implies(action, target string) bool {
+	if action == target || action == AnyAction {
+		return true
+	}
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	visited := map[string]struct{}{action: empty}
+	stack := []string{action}
+	for len(stack) > 0 {
+		a := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for implied := range h.implies[a] {
+			if implied == target {
+				return true
+			}
+			if _, ok := visited[implied]; !ok {
+				visited[implied] = empty
+				stack = append(stack, implied)
+			}
+		}
+	}
+	return false
+}
+
+// ResourcePermission grants an action on a resource.
+// The resource is a hierarchical path, e.g. `invoice` or `invoice:42` for an instance,
+// a permission on a resource is granted on every resource under it as well.
+// An empty action grants every action on the resource.
+type ResourcePermission struct {
+	Resource []string `json:"resource"`
+	Action   string   `json:"action,omitempty"`
+	Sep      string   `json:"sep"`
+	// Actions is the hierarchy used for matching, DefaultActions if nil.
+	Actions *ActionHierarchy `json:"-"`
+}
+
+// NewResourcePermission returns a permission for `action` on the `resource` path
+// separated by colons.
+func NewResourcePermission(resource, action string) *ResourcePermission {
+	return &ResourcePermission{
+		Resource: splitLayers(resource, ":"),
+		Action:   action,
+		Sep:      ":",
+	}
+}
+
+// ParseResourcePermission parses the DeepPermission form of `id`, e.g. `invoice:42:read`.
+// The last layer is considered an action if it is declared in DefaultActions.
+func ParseResourcePermission(id string) *ResourcePermission {
+	return ResourcePermissionFromDeep(&DeepPermission{IDStr: id, Sep: ":"}, nil)
+}
+
+// ResourcePermissionFromDeep converts a DeepPermission into a ResourcePermission.
+// The last layer is considered an action if it is declared in `actions`
+// (DefaultActions if nil).
+func ResourcePermissionFromDeep(p *DeepPermission, actions *ActionHierarchy) *ResourcePermission {
+	layers := splitLayers(p.IDStr, p.Sep)
+	rp := &ResourcePermission{Resource: layers, Sep: p.Sep, Actions: actions}
+	if n := len(layers); n > 1 && rp.actions().Has(layers[n-1]) {
+		rp.Resource, rp.Action = layers[:n-1], layers[n-1]
+	}
+	return rp
+}
+
+// Instance returns the permission for the instance `id` of the resource.
+func (p *ResourcePermission) Instance(id string) *ResourcePermission {
+	resource := make([]string, len(p.Resource), len(p.Resource)+1)
+	copy(resource, p.Resource)
+	return &ResourcePermission{
+		Resource: append(resource, id),
+		Action:   p.Action,
+		Sep:      p.Sep,
+		Actions:  p.Actions,
+	}
+}
+
+// ID returns the identity of permission in the DeepPermission form.
+func (p *ResourcePermission) ID() string {
+	id := strings.Join(p.Resource, p.Sep)
+	if p.Action == "" {
+		return id
+	}
+	if id == "" {
+		return p.Action
+	}
+	return id + p.Sep + p.Action
+}
+
+// Match another permission.
+// DeepPermission is matched by its parsed resource and action.
+func (p *ResourcePermission) Match(a Permission) bool {
+	if p.ID() == a.ID() {
+		return true
+	}
+	var q *ResourcePermission
+	switch v := unbind(a).(type) {
+	case *ResourcePermission:
+		q = v
+	case *DeepPermission:
+		q = ResourcePermissionFromDeep(v, p.Actions)
+	default:
+		return false
+	}
+
+	if len(p.Resource) > len(q.Resource) {
+		return false
+	}
+	for k, pv := range p.Resource {
+		if pv != q.Resource[k] {
+			return false
+		}
+	}
+	if p.Action == "" || p.Action == AnyAction {
+		return true
+	}
+	if q.Action == "" {
+		return false
+	}
+	return p.actions().Implies(p.Action, q.Action)
+}
+
+func (p *ResourcePermission) actions() *ActionHierarchy {
+	if p.Actions != nil {
+		return p.Actions
+	}
+	return DefaultActions
+}
+
+func splitLayers(id, sep string) []string {
+	if id == "" {
+		return nil
+	}
+	return strings.Split(id, sep)
+}
@@ -0,0 +1,110 @@
+package gorbac
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestActionHierarchy(t *testing.T) {
+	h := NewActionHierarchy().
+		Imply("manage", "write", "delete").
+		Imply("write", "read")
+
+	if !h.Implies("manage", "read") {
+		t.Fatal("[manage] should imply [read] transitively")
+	}
+	if !h.Implies("read", "read") || !h.Implies(AnyAction, "delete") {
+		t.Fatal("Action should imply itself and be implied by the wildcard")
+	}
+	if h.Implies("read", "write") || h.Implies("delete", "read") {
+		t.Fatal("Unexpected implication")
+	}
+	if !h.Has("delete") || h.Has("publish") {
+		t.Fatal("Declared actions mismatch")
+	}
+}
+
+func TestParseResourcePermission(t *testing.T) {
+	p := ParseResourcePermission("invoice:42:read")
+	if len(p.Resource) != 2 || p.Resource[1] != "42" || p.Action != "read" {
+		t.Fatalf("Unexpected parsing result: %+v", p)
+	}
+	if p.ID() != "invoice:42:read" {
+		t.Fatalf("[invoice:42:read] expected, but %s got", p.ID())
+	}
+
+	p = ParseResourcePermission("admin:users")
+	if len(p.Resource) != 2 || p.Action != "" {
+		t.Fatalf("Unexpected parsing result: %+v", p)
+	}
+
+	p = ResourcePermissionFromDeep(&DeepPermission{IDStr: "doc/7/publish", Sep: "/"},
+		NewActionHierarchy().Imply("publish"))
+	if p.ID() != "doc/7/publish" || p.Action != "publish" {
+		t.Fatalf("Unexpected parsing result: %+v", p)
+	}
+}
+
+func TestResourcePermission_Match(t *testing.T) {
+	invoiceRead := NewResourcePermission("invoice", "read")
+	invoiceWrite := NewResourcePermission("invoice", "write")
+	invoice42Read := invoiceRead.Instance("42")
+	invoice := NewResourcePermission("invoice", "")
+
+	if invoice42Read.ID() != "invoice:42:read" {
+		t.Fatalf("[invoice:42:read] expected, but %s got", invoice42Read.ID())
+	}
+	if !invoiceRead.Match(invoice42Read) {
+		t.Fatalf("%s should have the permission %s", invoiceRead.ID(), invoice42Read.ID())
+	}
+	if invoice42Read.Match(invoiceRead) {
+		t.Fatalf("%s should not have the permission %s", invoice42Read.ID(), invoiceRead.ID())
+	}
+	if !invoiceWrite.Match(invoice42Read) {
+		t.Fatalf("%s should imply %s", invoiceWrite.ID(), invoice42Read.ID())
+	}
+	if invoiceRead.Match(invoiceWrite.Instance("42")) {
+		t.Fatalf("%s should not imply write", invoiceRead.ID())
+	}
+	if !invoice.Match(invoiceWrite) || invoiceRead.Match(invoice) {
+		t.Fatal("Empty action should grant every action only")
+	}
+	if invoiceRead.Match(NewResourcePermission("order", "read")) {
+		t.Fatal("Resources should not match")
+	}
+	if invoiceRead.Match(NewPermission("invoice:42:read")) {
+		t.Fatal("Type assertion issue")
+	}
+
+	// DeepPermission interoperability
+	if !invoiceRead.Match(NewDeepPermission("invoice:42:read")) {
+		t.Fatalf("%s should have the deep permission", invoiceRead.ID())
+	}
+	if !NewDeepPermission("invoice").Match(invoice42Read) {
+		t.Fatalf("[invoice] should have the permission %s", invoice42Read.ID())
+	}
+	if NewDeepPermission("invoice:read").Match(invoice42Read) {
+		t.Fatalf("[invoice:read] should not match layers of %s", invoice42Read.ID())
+	}
+}
+
+func TestResourcePermission_Permit(t *testing.T) {
+	role := NewRole("accountant").Assign(NewResourcePermission("invoice", "write"))
+
+	if !role.Permit(NewDeepPermission("invoice:42:read")) {
+		t.Fatal("[accountant] should read invoices")
+	}
+	if role.Permit(NewDeepPermission("invoice:42:delete")) {
+		t.Fatal("[accountant] should not delete invoices")
+	}
+
+	text, err := json.Marshal(NewResourcePermission("invoice:42", "read"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var p ResourcePermission
+	assert(t, json.Unmarshal(text, &p))
+	if p.ID() != "invoice:42:read" {
+		t.Fatalf("[invoice:42:read] expected, but %s got", p.ID())
+	}
+}
@@ -30,6 +30,7 @@ type SimpleRole struct {
 	// IDStr is the identity of role
 	IDStr       string `json:"id"`
 	permissions Permissions
+	meta        Metadata
 }
 
 // ID returns the role's identity name.
@@ -37,6 +38,21 @@ func (role *SimpleRole) ID() string {
 	return role.IDStr
 }
 
+// Metadata returns a copy of the role's metadata.
+func (role *SimpleRole) Metadata() Metadata {
+	role.RLock()
+	defer role.RUnlock()
+	return role.meta.clone()
+}
+
+// SetMetadata replaces the role's metadata.
+func (role *SimpleRole) SetMetadata(m Metadata) *SimpleRole {
+	role.Lock()
+	role.meta = m.clone()
+	role.Unlock()
+	return role
+}
+
 // Assign a permission to the role.
 func (role *SimpleRole) Assign(p Permission) *SimpleRole {
 	role.Lock()
@@ -0,0 +1,37 @@
+package gorbac
+
+import "sort"
+
+// ScopedPermission is a permission of a role found by PermissionsUnder.
+type ScopedPermission struct {
+	Permission Permission
+	// Inherited is true if the permission is assigned to an ancestor of the role.
+	Inherited bool
+	// From is the role the permission is assigned to, the nearest one if there are several.
+	From string
+}
+
+// PermissionsUnder returns permissions of the role `id`, including inherited ones,
+// which are equal to, children of, or parents of `prefix` by Match,
+// e.g. `task`, `task:read` and `task:read:own` for the prefix `task:read`.
+// Inherited permissions are narrowed by edge filters.
+// The result is sorted by permission id.
+// If the role is not existing, an error will be returned.
+func (rbac *RBAC) PermissionsUnder(id string, prefix Permission) ([]ScopedPermission, error) {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	if _, ok := rbac.roles[id]; !ok {
+		return nil, roleNotExist("PermissionsUnder", id)
+	}
+
+	result := make([]ScopedPermission, 0)
+	for _, o := range rbac.visible(id) {
+		if o.p.Match(prefix) || prefix.Match(o.p) {
+			result = append(result, ScopedPermission{Permission: o.p, Inherited: o.from != id, From: o.from})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Permission.ID() < result[j].Permission.ID()
+	})
+	return result, nil
+}
@@ -0,0 +1,39 @@
+package gorbac
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRBAC_PermissionsUnder(t *testing.T) {
+	rbac := prepareTree(t)
+	assert(t, rbac.Assign("reporter", NewDeepPermission("task:read:own")))
+	assert(t, rbac.Assign("reporter", NewDeepPermission("taskforce")))
+
+	list, err := rbac.PermissionsUnder("reporter", NewDeepPermission("task:read"))
+	assert(t, err)
+
+	expected := []ScopedPermission{
+		{Permission: NewDeepPermission("task"), Inherited: true, From: "moderator"},
+		{Permission: NewDeepPermission("task:read"), Inherited: false, From: "reporter"},
+		{Permission: NewDeepPermission("task:read:own"), Inherited: false, From: "reporter"},
+	}
+	if len(list) != len(expected) {
+		t.Fatalf("%d permissions expected, but %v got", len(expected), list)
+	}
+	for i, e := range expected {
+		if list[i].Permission.ID() != e.Permission.ID() || list[i].Inherited != e.Inherited || list[i].From != e.From {
+			t.Fatalf("%+v expected, but %+v got", e, list[i])
+		}
+	}
+
+	list, err = rbac.PermissionsUnder("observer", NewDeepPermission("user"))
+	assert(t, err)
+	if len(list) != 1 || list[0].From != "admin" {
+		t.Fatalf("Unexpected permissions: %+v", list)
+	}
+
+	if _, err := rbac.PermissionsUnder("not-exist", NewDeepPermission("task")); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatalf("%s needed", ErrRoleNotExist)
+	}
+}
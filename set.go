@@ -0,0 +1,91 @@
+package gorbac
+
+// Union returns permissions of `ps` and `other`, an entry of `ps` wins on the same id.
+func (ps Permissions) Union(other Permissions) Permissions {
+	result := make(Permissions, len(ps)+len(other))
+	for id, p := range other {
+		result[id] = p
+	}
+	for id, p := range ps {
+		result[id] = p
+	}
+	return result
+}
+
+// Intersect returns permissions of `ps` whose ids are in `other` as well.
+func (ps Permissions) Intersect(other Permissions) Permissions {
+	result := make(Permissions)
+	for id, p := range ps {
+		if _, ok := other[id]; ok {
+			result[id] = p
+		}
+	}
+	return result
+}
+
+// Difference returns permissions of `ps` whose ids are not in `other`.
+func (ps Permissions) Difference(other Permissions) Permissions {
+	result := make(Permissions)
+	for id, p := range ps {
+		if _, ok := other[id]; !ok {
+			result[id] = p
+		}
+	}
+	return result
+}
+
+// IsSubset returns true if every id of `ps` is in `other`.
+func (ps Permissions) IsSubset(other Permissions) bool {
+	for id := range ps {
+		if _, ok := other[id]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Covers returns true if any permission of `ps` matches `p`,
+// e.g. DeepPermission `task` covers `task:read`.
+func (ps Permissions) Covers(p Permission) bool {
+	if p == nil {
+		return false
+	}
+	if e, ok := ps[p.ID()]; ok && e.Match(p) {
+		return true
+	}
+	for _, e := range ps {
+		if e.Match(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// CoversAll returns true if every permission of `other` is covered by `ps`.
+func (ps Permissions) CoversAll(other Permissions) bool {
+	for _, p := range other {
+		if !ps.Covers(p) {
+			return false
+		}
+	}
+	return true
+}
+
+// Minimize returns `ps` without the entries already implied by a broader DeepPermission of `ps`,
+// e.g. `task:read` is dropped if `task` is there. Checks against the result stay the same.
+func (ps Permissions) Minimize() Permissions {
+	result := make(Permissions, len(ps))
+	for id, p := range ps {
+		implied := false
+		for other, e := range ps {
+			if _, ok := unbind(e).(*DeepPermission); ok && other != id && e.Match(p) {
+				implied = true
+				break
+			}
+		}
+		if !implied {
+			result[id] = p
+		}
+	}
+	return result
+}
@@ -0,0 +1,80 @@
+package gorbac
+
+import "testing"
+
+func permissionSet(ids ...string) Permissions {
+	ps := make(Permissions, len(ids))
+	for _, id := range ids {
+		ps[id] = NewDeepPermission(id)
+	}
+	return ps
+}
+
+func permissionIDs(ps Permissions) []string {
+	var ids []string
+	for _, p := range ps.Sorted() {
+		ids = append(ids, p.ID())
+	}
+	return ids
+}
+
+func TestPermissions_Algebra(t *testing.T) {
+	a := permissionSet("task", "task:read", "user:read")
+	b := permissionSet("task:read", "system")
+
+	if ids := permissionIDs(a.Union(b)); !equalStrings(ids, []string{"system", "task", "task:read", "user:read"}) {
+		t.Fatalf("Unexpected union: %v", ids)
+	}
+	if ids := permissionIDs(a.Intersect(b)); !equalStrings(ids, []string{"task:read"}) {
+		t.Fatalf("Unexpected intersection: %v", ids)
+	}
+	if ids := permissionIDs(a.Difference(b)); !equalStrings(ids, []string{"task", "user:read"}) {
+		t.Fatalf("Unexpected difference: %v", ids)
+	}
+	if !permissionSet("task").IsSubset(a) || b.IsSubset(a) || !(Permissions{}).IsSubset(b) {
+		t.Fatal("IsSubset works incorrectly")
+	}
+}
+
+func TestPermissions_Covers(t *testing.T) {
+	ps := permissionSet("task", "user:read")
+	ps["stats"] = NewPermission("stats")
+
+	if !ps.Covers(NewDeepPermission("task:delete")) || !ps.Covers(NewPermission("stats")) {
+		t.Fatal("Permissions should be covered")
+	}
+	if ps.Covers(NewDeepPermission("user")) || ps.Covers(NewPermission("stats:daily")) || ps.Covers(nil) {
+		t.Fatal("Permissions should not be covered")
+	}
+	if !ps.CoversAll(permissionSet("task:read", "task:create", "user:read")) || ps.CoversAll(permissionSet("user:write")) {
+		t.Fatal("CoversAll works incorrectly")
+	}
+
+	rbac := prepareTree(t)
+	if !rbac.Permissions("root").IsSubset(rbac.Permissions("admin")) || !rbac.Permissions("observer").CoversAll(rbac.Permissions("moderator")) {
+		t.Fatal("Children should cover parents")
+	}
+}
+
+func TestPermissions_Minimize(t *testing.T) {
+	ps := permissionSet("task", "task:read", "task:read:own", "user:read", "user:write")
+	ps["task:stats"] = NewPermission("task:stats")
+	ps["user"] = Describe(NewDeepPermission("user"), Metadata{Title: "Users"})
+
+	min := ps.Minimize()
+	// DeepPermission doesn't match SimplePermission, so task:stats is kept
+	if ids := permissionIDs(min); !equalStrings(ids, []string{"task", "task:stats", "user"}) {
+		t.Fatalf("Unexpected minimized set: %v", ids)
+	}
+	for _, p := range ps {
+		if !min.Covers(p) {
+			t.Fatalf("%s should stay covered", p.ID())
+		}
+	}
+
+	ps = permissionSet("user:read")
+	ps["user"] = NewPermission("user")
+	if ids := permissionIDs(ps.Minimize()); len(ids) != 2 {
+		t.Fatalf("SimplePermission should not imply others: %v", ids)
+	}
+}
@@ -0,0 +1,395 @@
+package gorbac
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sort"
+)
+
+// Errors of the binary snapshot format
+var (
+	// ErrInvalidSnapshot occurred if a snapshot is malformed or its checksum mismatches
+	ErrInvalidSnapshot = errors.New("invalid snapshot")
+	// ErrSnapshotVersion occurred if a snapshot was written by an unsupported version
+	ErrSnapshotVersion = errors.New("unsupported snapshot version")
+)
+
+var snapshotMagic = [4]byte{'R', 'B', 'A', 'C'}
+
+// snapshotVersion is written by WriteSnapshot.
+const snapshotVersion = 1
+
+// kind tags of permissions in a snapshot
+var snapshotKinds = []string{"", KindSimple, KindDeep, KindResource, KindTemplate}
+
+func snapshotKind(kind string) byte {
+	for i, k := range snapshotKinds {
+		if k == kind && i > 0 {
+			return byte(i)
+		}
+	}
+	return 0
+}
+
+// WriteSnapshot encodes the whole `rbac` in the compact binary form:
+//
+//	magic "RBAC", version byte,
+//	string table: count, then length-prefixed strings,
+//	roles: count, then role id index, metadata, inheritance depth, permission count,
+//	then permission and metadata of each permission,
+//	permission: kind tag, id index, separator index, and for resource permissions
+//	resource index and action index,
+//	metadata: title index, description index, label count, then name index and value index,
+//	edges: count, then child index, parent index, inheritance depth, filter count,
+//	then each filter permission,
+//	subject assignments: count, then subject index and role index,
+//	CRC-32 (IEEE) of all previous bytes.
+//
+// Numbers are unsigned varints, indexes point into the string table.
+func WriteSnapshot(w io.Writer, rbac *RBAC) error {
+	policy, err := rbac.Export()
+	if err != nil {
+		return err
+	}
+
+	strs := make(map[string]uint64)
+	var table []string
+	intern := func(s string) {
+		if _, ok := strs[s]; !ok {
+			strs[s] = uint64(len(table))
+			table = append(table, s)
+		}
+	}
+	internMetadata := func(m Metadata) {
+		intern(m.Title)
+		intern(m.Description)
+		for name, value := range m.Labels {
+			intern(name)
+			intern(value)
+		}
+	}
+	internPermission := func(p PermissionRecord) {
+		intern(p.ID)
+		intern(p.Sep)
+		intern(p.Resource)
+		intern(p.Action)
+	}
+	edges := 0
+	for _, role := range policy.Roles {
+		intern(role.ID)
+		internMetadata(role.Metadata)
+		for _, p := range role.Permissions {
+			internPermission(p)
+			internMetadata(p.Metadata)
+		}
+		for _, e := range role.Edges {
+			for _, p := range e.Filter {
+				internPermission(p)
+			}
+		}
+		edges += len(role.Parents)
+	}
+	subjects := make([]string, 0, len(policy.Subjects))
+	assignments := 0
+	for subject, roles := range policy.Subjects {
+		subjects = append(subjects, subject)
+		assignments += len(roles)
+	}
+	sort.Strings(subjects)
+	for _, subject := range subjects {
+		intern(subject)
+	}
+
+	crc := crc32.NewIEEE()
+	bw := bufio.NewWriter(io.MultiWriter(w, crc))
+	enc := &snapshotEncoder{w: bw}
+
+	enc.bytes(snapshotMagic[:])
+	enc.bytes([]byte{snapshotVersion})
+	enc.uint(uint64(len(table)))
+	for _, s := range table {
+		enc.uint(uint64(len(s)))
+		enc.bytes([]byte(s))
+	}
+	metadata := func(m Metadata) {
+		enc.uint(strs[m.Title])
+		enc.uint(strs[m.Description])
+		enc.uint(uint64(len(m.Labels)))
+		for _, name := range m.sortedLabels() {
+			enc.uint(strs[name])
+			enc.uint(strs[m.Labels[name]])
+		}
+	}
+	permission := func(p PermissionRecord) {
+		enc.bytes([]byte{snapshotKind(p.Kind)})
+		enc.uint(strs[p.ID])
+		enc.uint(strs[p.Sep])
+		if p.Kind == KindResource {
+			enc.uint(strs[p.Resource])
+			enc.uint(strs[p.Action])
+		}
+	}
+	enc.uint(uint64(len(policy.Roles)))
+	for _, role := range policy.Roles {
+		enc.uint(strs[role.ID])
+		metadata(role.Metadata)
+		enc.uint(uint64(role.Depth))
+		enc.uint(uint64(len(role.Permissions)))
+		for _, p := range role.Permissions {
+			permission(p)
+			metadata(p.Metadata)
+		}
+	}
+	enc.uint(uint64(edges))
+	for _, role := range policy.Roles {
+		options := make(map[string]EdgeRecord, len(role.Edges))
+		for _, e := range role.Edges {
+			options[e.Parent] = e
+		}
+		for _, parent := range role.Parents {
+			enc.uint(strs[role.ID])
+			enc.uint(strs[parent])
+			enc.uint(uint64(options[parent].Depth))
+			enc.uint(uint64(len(options[parent].Filter)))
+			for _, p := range options[parent].Filter {
+				permission(p)
+			}
+		}
+	}
+	enc.uint(uint64(assignments))
+	for _, subject := range subjects {
+		for _, id := range policy.Subjects[subject] {
+			enc.uint(strs[subject])
+			enc.uint(strs[id])
+		}
+	}
+	if enc.err != nil {
+		return enc.err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	var sum [4]byte
+	binary.BigEndian.PutUint32(sum[:], crc.Sum32())
+	_, err = w.Write(sum[:])
+	return err
+}
+
+type snapshotEncoder struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (e *snapshotEncoder) bytes(b []byte) {
+	if e.err == nil {
+		_, e.err = e.w.Write(b)
+	}
+}
+
+func (e *snapshotEncoder) uint(v uint64) {
+	e.bytes(e.buf[:binary.PutUvarint(e.buf[:], v)])
+}
+
+// ReadSnapshot decodes a snapshot written by WriteSnapshot into a new RBAC structure.
+// Roles are created as SimpleRole, no change events are emitted.
+// Nothing is returned unless the checksum matches.
+func ReadSnapshot(r io.Reader) (*RBAC, error) {
+	crc := crc32.NewIEEE()
+	dec := &snapshotDecoder{r: bufio.NewReader(r), crc: crc}
+
+	var head [5]byte
+	dec.read(head[:])
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	if [4]byte{head[0], head[1], head[2], head[3]} != snapshotMagic {
+		return nil, ErrInvalidSnapshot
+	}
+	if head[4] != snapshotVersion {
+		return nil, fmt.Errorf("%w: %d", ErrSnapshotVersion, head[4])
+	}
+
+	table := make([]string, dec.count())
+	for i := range table {
+		b := make([]byte, dec.count())
+		dec.read(b)
+		table[i] = string(b)
+	}
+
+	metadata := func() Metadata {
+		var m Metadata
+		m.Title, m.Description = dec.str(table), dec.str(table)
+		labels := dec.count()
+		for k := 0; k < labels && dec.err == nil; k++ {
+			if m.Labels == nil {
+				m.Labels = make(map[string]string, labels)
+			}
+			name := dec.str(table)
+			m.Labels[name] = dec.str(table)
+		}
+		return m
+	}
+
+	rbac := New()
+	roles := dec.count()
+	for i := 0; i < roles && dec.err == nil; i++ {
+		role := NewRole(dec.str(table))
+		role.meta = metadata()
+		if depth := dec.count(); depth > 0 {
+			rbac.depths[role.IDStr] = depth
+		}
+		permissions := dec.count()
+		for j := 0; j < permissions && dec.err == nil; j++ {
+			p, err := dec.permission(table, metadata)
+			if dec.err != nil {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			role.permissions[p.ID()] = p
+		}
+		rbac.roles[role.IDStr] = role
+	}
+	edges := dec.count()
+	for i := 0; i < edges && dec.err == nil; i++ {
+		child, parent := dec.str(table), dec.str(table)
+		_, okChild := rbac.roles[child]
+		_, okParent := rbac.roles[parent]
+		if !okChild || !okParent {
+			dec.fail()
+			break
+		}
+		depth := dec.count()
+		var filter []Permission
+		n := dec.count()
+		for j := 0; j < n && dec.err == nil; j++ {
+			p, err := dec.permission(table, nil)
+			if dec.err != nil {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			filter = append(filter, p)
+		}
+		rbac.setEdge(child, parent, []EdgeOption{InheritDepth(depth), InheritOnly(filter...)})
+	}
+	assignments := dec.count()
+	for i := 0; i < assignments && dec.err == nil; i++ {
+		subject, id := dec.str(table), dec.str(table)
+		if _, ok := rbac.roles[id]; !ok {
+			dec.fail()
+			break
+		}
+		rbac.assignSubject(subject, id)
+	}
+	if dec.err != nil {
+		return nil, dec.err
+	}
+
+	expected := crc.Sum32()
+	var sum [4]byte
+	if _, err := io.ReadFull(dec.r, sum[:]); err != nil || binary.BigEndian.Uint32(sum[:]) != expected {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrInvalidSnapshot)
+	}
+	return rbac, nil
+}
+
+type snapshotDecoder struct {
+	r   *bufio.Reader
+	crc hash.Hash32
+	err error
+}
+
+func (d *snapshotDecoder) fail() {
+	if d.err == nil {
+		d.err = ErrInvalidSnapshot
+	}
+}
+
+func (d *snapshotDecoder) read(b []byte) {
+	if d.err != nil {
+		return
+	}
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		d.fail()
+		return
+	}
+	d.crc.Write(b)
+}
+
+func (d *snapshotDecoder) uint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	var buf [binary.MaxVarintLen64]byte
+	for i := range buf {
+		c, err := d.r.ReadByte()
+		if err != nil {
+			d.fail()
+			return 0
+		}
+		buf[i] = c
+		if c < 0x80 {
+			d.crc.Write(buf[:i+1])
+			v, n := binary.Uvarint(buf[:i+1])
+			if n <= 0 {
+				d.fail()
+			}
+			return v
+		}
+	}
+	d.fail()
+	return 0
+}
+
+// maxSnapshotCount limits lengths read from a snapshot to protect against huge allocations.
+const maxSnapshotCount = 1 << 24
+
+func (d *snapshotDecoder) count() int {
+	v := d.uint()
+	if v > maxSnapshotCount {
+		d.fail()
+		return 0
+	}
+	return int(v)
+}
+
+func (d *snapshotDecoder) str(table []string) string {
+	i := d.uint()
+	if d.err != nil {
+		return ""
+	}
+	if i >= uint64(len(table)) {
+		d.fail()
+		return ""
+	}
+	return table[i]
+}
+
+// permission decodes a permission followed by metadata read by `metadata`, if given.
+func (d *snapshotDecoder) permission(table []string, metadata func() Metadata) (Permission, error) {
+	var tag [1]byte
+	d.read(tag[:])
+	record := PermissionRecord{ID: d.str(table), Sep: d.str(table)}
+	if int(tag[0]) < len(snapshotKinds) {
+		record.Kind = snapshotKinds[tag[0]]
+	}
+	if record.Kind == KindResource {
+		record.Resource, record.Action = d.str(table), d.str(table)
+	}
+	if metadata != nil {
+		record.Metadata = metadata()
+	}
+	if d.err != nil {
+		return nil, nil
+	}
+	return record.Permission()
+}
@@ -0,0 +1,137 @@
+package gorbac
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestSnapshot(t *testing.T) {
+	rbac := prepareTree(t)
+	assert(t, rbac.Assign("reporter", NewPermission("simple")))
+	assert(t, rbac.Assign("reporter", NewResourcePermission("invoice", "write")))
+	assert(t, rbac.Assign("reporter", NewResourcePermission("invoice", "approve")))
+	assert(t, rbac.Assign("reporter", MustTemplatePermission("user:{subject}")))
+	assert(t, rbac.AssignSubject("alice", "reporter"))
+	assert(t, rbac.AssignSubject("alice", "admin"))
+	assert(t, rbac.AssignSubject("bob", "observer"))
+
+	var buf bytes.Buffer
+	assert(t, WriteSnapshot(&buf, rbac))
+	data := buf.Bytes()
+
+	cp, err := ReadSnapshot(bytes.NewReader(data))
+	assert(t, err)
+	expected, err := rbac.Export()
+	assert(t, err)
+	got, err := cp.Export()
+	assert(t, err)
+	if !equalPolicies(expected, got) {
+		t.Fatalf("Policies differ:\n%+v\n%+v", expected, got)
+	}
+	if list, _ := cp.Children("moderator"); len(list) != 2 {
+		t.Fatal("Reverse index should be built")
+	}
+
+	corrupted := append([]byte(nil), data...)
+	corrupted[len(corrupted)/2] ^= 0xff
+	if _, err := ReadSnapshot(bytes.NewReader(corrupted)); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("%s needed, but %v got", ErrInvalidSnapshot, err)
+	}
+	if _, err := ReadSnapshot(bytes.NewReader(data[:len(data)-1])); !errors.Is(err, ErrInvalidSnapshot) {
+		t.Fatalf("%s needed, but %v got", ErrInvalidSnapshot, err)
+	}
+
+	if !cp.IsGranted("reporter", NewResourcePermission("invoice:42", "approve"), nil) {
+		t.Fatal("Custom actions should be kept")
+	}
+	roles := cp.SubjectRoles("alice")
+	sort.Strings(roles)
+	if !equalStrings(roles, []string{"admin", "reporter"}) {
+		t.Fatal("Subjects should be kept")
+	}
+
+	future := append([]byte(nil), data...)
+	future[4] = snapshotVersion + 1
+	if _, err := ReadSnapshot(bytes.NewReader(future)); !errors.Is(err, ErrSnapshotVersion) {
+		t.Fatalf("%s needed, but %v got", ErrSnapshotVersion, err)
+	}
+}
+
+func TestSnapshot_Metadata(t *testing.T) {
+	rbac := prepareTree(t)
+	m := Metadata{Title: "Administrator", Description: "Manages users", Labels: map[string]string{"team": "billing", "owner": "ops"}}
+	assert(t, rbac.SetMetadata("admin", m))
+	assert(t, rbac.Assign("admin", Describe(NewDeepPermission("user:export"), Metadata{Title: "Export users"})))
+
+	var buf bytes.Buffer
+	assert(t, WriteSnapshot(&buf, rbac))
+	cp, err := ReadSnapshot(&buf)
+	assert(t, err)
+
+	expected, err := rbac.Export()
+	assert(t, err)
+	got, err := cp.Export()
+	assert(t, err)
+	if !equalPolicies(expected, got) {
+		t.Fatalf("Policies differ:\n%+v\n%+v", expected, got)
+	}
+}
+
+func largeRBAC(b *testing.B, n int) *RBAC {
+	rbac := New()
+	for i := 0; i < n; i++ {
+		role := NewRole("role-" + strconv.Itoa(i))
+		role.Assign(NewDeepPermission("resource-" + strconv.Itoa(i%100) + ":read"))
+		role.Assign(NewDeepPermission("resource-" + strconv.Itoa(i) + ":write"))
+		if err := rbac.Add(role); err != nil {
+			b.Fatal(err)
+		}
+		if i > 0 {
+			if err := rbac.SetParent(role.IDStr, "role-"+strconv.Itoa((i-1)/2)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	return rbac
+}
+
+func BenchmarkReadSnapshot(b *testing.B) {
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, largeRBAC(b, 10000)); err != nil {
+		b.Fatal(err)
+	}
+	data := buf.Bytes()
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ReadSnapshot(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReadJSONPolicy(b *testing.B) {
+	policy, err := largeRBAC(b, 10000).Export()
+	if err != nil {
+		b.Fatal(err)
+	}
+	data, err := json.Marshal(policy)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.SetBytes(int64(len(data)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p, err := ReadPolicy(bytes.NewReader(data))
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := NewFromPolicy(p); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,558 @@
+package gorbac
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Storage persists policies.
+type Storage interface {
+	// Load returns the whole stored policy.
+	Load(ctx context.Context) (*Policy, error)
+	// Save replaces the stored policy.
+	Save(ctx context.Context, policy *Policy) error
+	// Apply stores changes incrementally.
+	Apply(ctx context.Context, events []Event) error
+}
+
+// LoadRBAC returns a RBAC structure filled by the policy stored in `s`.
+func LoadRBAC(ctx context.Context, s Storage) (*RBAC, error) {
+	policy, err := s.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromPolicy(policy)
+}
+
+// SaveRBAC replaces the policy stored in `s` by the policy of `rbac`.
+func SaveRBAC(ctx context.Context, s Storage, rbac *RBAC) error {
+	policy, err := rbac.Export()
+	if err != nil {
+		return err
+	}
+	return s.Save(ctx, policy)
+}
+
+// sqlMigrations are applied in order, the version of a migration is its index plus one.
+var sqlMigrations = [][]string{
+	{
+		`CREATE TABLE {prefix}roles (
+			id VARCHAR(255) NOT NULL PRIMARY KEY
+		)`,
+		`CREATE TABLE {prefix}permissions (
+			role_id VARCHAR(255) NOT NULL,
+			id VARCHAR(255) NOT NULL,
+			kind VARCHAR(32) NOT NULL,
+			sep VARCHAR(16) NOT NULL DEFAULT '',
+			PRIMARY KEY (role_id, id)
+		)`,
+		`CREATE TABLE {prefix}parents (
+			role_id VARCHAR(255) NOT NULL,
+			parent_id VARCHAR(255) NOT NULL,
+			PRIMARY KEY (role_id, parent_id)
+		)`,
+	},
+	{
+		`CREATE TABLE {prefix}subjects (
+			subject VARCHAR(255) NOT NULL,
+			role_id VARCHAR(255) NOT NULL,
+			PRIMARY KEY (subject, role_id)
+		)`,
+	},
+	{
+		`ALTER TABLE {prefix}roles ADD COLUMN title VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE {prefix}roles ADD COLUMN description VARCHAR(1024) NOT NULL DEFAULT ''`,
+		`ALTER TABLE {prefix}permissions ADD COLUMN title VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE {prefix}permissions ADD COLUMN description VARCHAR(1024) NOT NULL DEFAULT ''`,
+		// labels of a role are stored with an empty permission_id
+		`CREATE TABLE {prefix}labels (
+			role_id VARCHAR(255) NOT NULL,
+			permission_id VARCHAR(255) NOT NULL DEFAULT '',
+			name VARCHAR(255) NOT NULL,
+			value VARCHAR(255) NOT NULL,
+			PRIMARY KEY (role_id, permission_id, name)
+		)`,
+	},
+	{
+		`ALTER TABLE {prefix}roles ADD COLUMN depth INTEGER NOT NULL DEFAULT 0`,
+		`ALTER TABLE {prefix}parents ADD COLUMN depth INTEGER NOT NULL DEFAULT 0`,
+	},
+	{
+		`CREATE TABLE {prefix}edge_filters (
+			role_id VARCHAR(255) NOT NULL,
+			parent_id VARCHAR(255) NOT NULL,
+			id VARCHAR(255) NOT NULL,
+			kind VARCHAR(32) NOT NULL,
+			sep VARCHAR(16) NOT NULL DEFAULT '',
+			PRIMARY KEY (role_id, parent_id, id)
+		)`,
+	},
+	{
+		`ALTER TABLE {prefix}permissions ADD COLUMN resource VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE {prefix}permissions ADD COLUMN action VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE {prefix}edge_filters ADD COLUMN resource VARCHAR(255) NOT NULL DEFAULT ''`,
+		`ALTER TABLE {prefix}edge_filters ADD COLUMN action VARCHAR(255) NOT NULL DEFAULT ''`,
+	},
+}
+
+// SQLStorage stores policies in a database through `database/sql`.
+// The schema is created by Migrate.
+type SQLStorage struct {
+	db *sql.DB
+	// Prefix of the table names, `rbac_` by default.
+	Prefix string
+	// Placeholder returns the bind parameter `n` (starting from 1), `?` by default.
+	Placeholder func(n int) string
+}
+
+// NewSQLStorage returns a storage using `db`.
+func NewSQLStorage(db *sql.DB) *SQLStorage {
+	return &SQLStorage{
+		db:          db,
+		Prefix:      "rbac_",
+		Placeholder: func(int) string { return "?" },
+	}
+}
+
+// DollarPlaceholder numbers bind parameters as PostgreSQL does: `$1`, `$2`.
+func DollarPlaceholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+// query replaces `{prefix}` and `?` of `q` by the table prefix and placeholders.
+func (s *SQLStorage) query(q string) string {
+	q = strings.ReplaceAll(q, "{prefix}", s.Prefix)
+	var b strings.Builder
+	n := 0
+	for _, c := range q {
+		if c == '?' {
+			n++
+			b.WriteString(s.Placeholder(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// Migrate creates or upgrades the schema and returns its version.
+func (s *SQLStorage) Migrate(ctx context.Context) (int, error) {
+	if _, err := s.db.ExecContext(ctx, s.query(
+		`CREATE TABLE IF NOT EXISTS {prefix}migrations (version INTEGER NOT NULL PRIMARY KEY)`,
+	)); err != nil {
+		return 0, err
+	}
+	var version int
+	if err := s.db.QueryRowContext(ctx, s.query(
+		`SELECT COALESCE(MAX(version), 0) FROM {prefix}migrations`,
+	)).Scan(&version); err != nil {
+		return 0, err
+	}
+	for ; version < len(sqlMigrations); version++ {
+		err := s.tx(ctx, func(tx *sql.Tx) error {
+			for _, q := range sqlMigrations[version] {
+				if _, err := tx.ExecContext(ctx, s.query(q)); err != nil {
+					return err
+				}
+			}
+			_, err := tx.ExecContext(ctx, s.query(`INSERT INTO {prefix}migrations (version) VALUES (?)`), version+1)
+			return err
+		})
+		if err != nil {
+			return version, fmt.Errorf("migration %d: %w", version+1, err)
+		}
+	}
+	return version, nil
+}
+
+func (s *SQLStorage) tx(ctx context.Context, f func(*sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := f(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Load returns the stored policy, roles, permissions and parents are sorted by id.
+func (s *SQLStorage) Load(ctx context.Context) (*Policy, error) {
+	policy := &Policy{}
+	index := make(map[string]int)
+
+	rows, err := s.db.QueryContext(ctx, s.query(`SELECT id, title, description, depth FROM {prefix}roles ORDER BY id`))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var r RoleRecord
+		if err := rows.Scan(&r.ID, &r.Title, &r.Description, &r.Depth); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		index[r.ID] = len(policy.Roles)
+		policy.Roles = append(policy.Roles, r)
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, s.query(`SELECT role_id, id, kind, sep, resource, action, title, description FROM {prefix}permissions ORDER BY role_id, id`))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var roleID string
+		var r PermissionRecord
+		if err := rows.Scan(&roleID, &r.ID, &r.Kind, &r.Sep, &r.Resource, &r.Action, &r.Title, &r.Description); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if i, ok := index[roleID]; ok {
+			policy.Roles[i].Permissions = append(policy.Roles[i].Permissions, r)
+		}
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, s.query(`SELECT role_id, permission_id, name, value FROM {prefix}labels`))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var roleID, permissionID, name, value string
+		if err := rows.Scan(&roleID, &permissionID, &name, &value); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		i, ok := index[roleID]
+		if !ok {
+			continue
+		}
+		m := &policy.Roles[i].Metadata
+		if permissionID != "" {
+			m = nil
+			for j := range policy.Roles[i].Permissions {
+				if policy.Roles[i].Permissions[j].ID == permissionID {
+					m = &policy.Roles[i].Permissions[j].Metadata
+				}
+			}
+		}
+		if m != nil {
+			if m.Labels == nil {
+				m.Labels = make(map[string]string)
+			}
+			m.Labels[name] = value
+		}
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, s.query(`SELECT role_id, parent_id, depth FROM {prefix}parents ORDER BY role_id, parent_id`))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var roleID, parentID string
+		var depth int
+		if err := rows.Scan(&roleID, &parentID, &depth); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if i, ok := index[roleID]; ok {
+			policy.Roles[i].Parents = append(policy.Roles[i].Parents, parentID)
+			if depth > 0 {
+				policy.Roles[i].Edges = append(policy.Roles[i].Edges, EdgeRecord{Parent: parentID, Depth: depth})
+			}
+		}
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, s.query(`SELECT role_id, parent_id, id, kind, sep, resource, action FROM {prefix}edge_filters ORDER BY role_id, parent_id, id`))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var roleID, parentID string
+		var r PermissionRecord
+		if err := rows.Scan(&roleID, &parentID, &r.ID, &r.Kind, &r.Sep, &r.Resource, &r.Action); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		i, ok := index[roleID]
+		if !ok {
+			continue
+		}
+		edges := policy.Roles[i].Edges
+		j := sort.Search(len(edges), func(j int) bool { return edges[j].Parent >= parentID })
+		if j == len(edges) || edges[j].Parent != parentID {
+			edges = append(edges, EdgeRecord{})
+			copy(edges[j+1:], edges[j:])
+			edges[j] = EdgeRecord{Parent: parentID}
+		}
+		edges[j].Filter = append(edges[j].Filter, r)
+		policy.Roles[i].Edges = edges
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.QueryContext(ctx, s.query(`SELECT subject, role_id FROM {prefix}subjects ORDER BY subject, role_id`))
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var subject, roleID string
+		if err := rows.Scan(&subject, &roleID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if _, ok := index[roleID]; ok {
+			if policy.Subjects == nil {
+				policy.Subjects = make(map[string][]string)
+			}
+			policy.Subjects[subject] = append(policy.Subjects[subject], roleID)
+		}
+	}
+	if err := closeRows(rows); err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+func closeRows(rows *sql.Rows) error {
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	return rows.Close()
+}
+
+// Save replaces the stored policy in a single transaction.
+func (s *SQLStorage) Save(ctx context.Context, policy *Policy) error {
+	return s.tx(ctx, func(tx *sql.Tx) error {
+		for _, table := range []string{"edge_filters", "labels", "subjects", "parents", "permissions", "roles"} {
+			if _, err := tx.ExecContext(ctx, s.query(`DELETE FROM {prefix}`+table)); err != nil {
+				return err
+			}
+		}
+		for _, role := range policy.Roles {
+			if err := s.insertRole(ctx, tx, role.ID, role.Metadata, role.Permissions); err != nil {
+				return err
+			}
+			if role.Depth > 0 {
+				if err := s.setDepth(ctx, tx, role.ID, role.Depth); err != nil {
+					return err
+				}
+			}
+		}
+		for _, role := range policy.Roles {
+			edges := make(map[string]EdgeRecord, len(role.Edges))
+			for _, e := range role.Edges {
+				edges[e.Parent] = e
+			}
+			for _, parent := range role.Parents {
+				if err := s.insertParent(ctx, tx, role.ID, parent, edges[parent]); err != nil {
+					return err
+				}
+				delete(edges, parent)
+			}
+			for _, e := range role.Edges {
+				if _, ok := edges[e.Parent]; ok {
+					if err := s.insertParent(ctx, tx, role.ID, e.Parent, e); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		for subject, roles := range policy.Subjects {
+			for _, id := range roles {
+				if err := s.insertSubject(ctx, tx, subject, id); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// Apply stores changes in a single transaction.
+func (s *SQLStorage) Apply(ctx context.Context, events []Event) error {
+	return s.tx(ctx, func(tx *sql.Tx) error {
+		for _, e := range events {
+			if err := s.apply(ctx, tx, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *SQLStorage) apply(ctx context.Context, tx *sql.Tx, e Event) error {
+	switch e.Op {
+	case EventAddRole:
+		var m Metadata
+		if e.Metadata != nil {
+			m = *e.Metadata
+		}
+		return s.insertRole(ctx, tx, e.Role, m, e.Permissions)
+	case EventRemoveRole:
+		for _, q := range []string{
+			`DELETE FROM {prefix}edge_filters WHERE role_id = ? OR parent_id = ?`,
+			`DELETE FROM {prefix}parents WHERE role_id = ? OR parent_id = ?`,
+		} {
+			if _, err := tx.ExecContext(ctx, s.query(q), e.Role, e.Role); err != nil {
+				return err
+			}
+		}
+		for _, q := range []string{
+			`DELETE FROM {prefix}labels WHERE role_id = ?`,
+			`DELETE FROM {prefix}subjects WHERE role_id = ?`,
+			`DELETE FROM {prefix}permissions WHERE role_id = ?`,
+			`DELETE FROM {prefix}roles WHERE id = ?`,
+		} {
+			if _, err := tx.ExecContext(ctx, s.query(q), e.Role); err != nil {
+				return err
+			}
+		}
+		return nil
+	case EventSetParent, EventRemoveParent:
+		for _, q := range []string{
+			`DELETE FROM {prefix}edge_filters WHERE role_id = ? AND parent_id = ?`,
+			`DELETE FROM {prefix}parents WHERE role_id = ? AND parent_id = ?`,
+		} {
+			if _, err := tx.ExecContext(ctx, s.query(q), e.Role, e.Parent); err != nil {
+				return err
+			}
+		}
+		if e.Op == EventRemoveParent {
+			return nil
+		}
+		return s.insertParent(ctx, tx, e.Role, e.Parent, EdgeRecord{Parent: e.Parent, Depth: e.Depth, Filter: e.Permissions})
+	case EventAssign, EventRevoke:
+		for _, p := range e.Permissions {
+			for _, q := range []string{
+				`DELETE FROM {prefix}labels WHERE role_id = ? AND permission_id = ?`,
+				`DELETE FROM {prefix}permissions WHERE role_id = ? AND id = ?`,
+			} {
+				if _, err := tx.ExecContext(ctx, s.query(q), e.Role, p.ID); err != nil {
+					return err
+				}
+			}
+			if e.Op == EventAssign {
+				if err := s.insertPermission(ctx, tx, e.Role, p); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case EventAssignSubject:
+		if _, err := tx.ExecContext(ctx, s.query(
+			`DELETE FROM {prefix}subjects WHERE subject = ? AND role_id = ?`,
+		), e.Subject, e.Role); err != nil {
+			return err
+		}
+		return s.insertSubject(ctx, tx, e.Subject, e.Role)
+	case EventUnassignSubject:
+		_, err := tx.ExecContext(ctx, s.query(
+			`DELETE FROM {prefix}subjects WHERE subject = ? AND role_id = ?`,
+		), e.Subject, e.Role)
+		return err
+	case EventSetMetadata:
+		var m Metadata
+		if e.Metadata != nil {
+			m = *e.Metadata
+		}
+		if _, err := tx.ExecContext(ctx, s.query(
+			`UPDATE {prefix}roles SET title = ?, description = ? WHERE id = ?`,
+		), m.Title, m.Description, e.Role); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, s.query(
+			`DELETE FROM {prefix}labels WHERE role_id = ? AND permission_id = ''`,
+		), e.Role); err != nil {
+			return err
+		}
+		return s.insertLabels(ctx, tx, e.Role, "", m.Labels)
+	case EventSetDepth:
+		return s.setDepth(ctx, tx, e.Role, e.Depth)
+	}
+	return fmt.Errorf("unknown event: %s", e.Op)
+}
+
+func (s *SQLStorage) insertRole(ctx context.Context, tx *sql.Tx, id string, m Metadata, permissions []PermissionRecord) error {
+	if _, err := tx.ExecContext(ctx, s.query(
+		`INSERT INTO {prefix}roles (id, title, description) VALUES (?, ?, ?)`,
+	), id, m.Title, m.Description); err != nil {
+		return err
+	}
+	if err := s.insertLabels(ctx, tx, id, "", m.Labels); err != nil {
+		return err
+	}
+	for _, p := range permissions {
+		if err := s.insertPermission(ctx, tx, id, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStorage) insertPermission(ctx context.Context, tx *sql.Tx, roleID string, p PermissionRecord) error {
+	if _, err := tx.ExecContext(ctx, s.query(
+		`INSERT INTO {prefix}permissions (role_id, id, kind, sep, resource, action, title, description) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+	), roleID, p.ID, p.Kind, p.Sep, p.Resource, p.Action, p.Title, p.Description); err != nil {
+		return err
+	}
+	return s.insertLabels(ctx, tx, roleID, p.ID, p.Labels)
+}
+
+func (s *SQLStorage) insertLabels(ctx context.Context, tx *sql.Tx, roleID, permissionID string, labels map[string]string) error {
+	for name, value := range labels {
+		if _, err := tx.ExecContext(ctx, s.query(
+			`INSERT INTO {prefix}labels (role_id, permission_id, name, value) VALUES (?, ?, ?, ?)`,
+		), roleID, permissionID, name, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStorage) insertParent(ctx context.Context, tx *sql.Tx, roleID, parentID string, e EdgeRecord) error {
+	if _, err := tx.ExecContext(ctx, s.query(
+		`INSERT INTO {prefix}parents (role_id, parent_id, depth) VALUES (?, ?, ?)`,
+	), roleID, parentID, e.Depth); err != nil {
+		return err
+	}
+	for _, p := range e.Filter {
+		if _, err := tx.ExecContext(ctx, s.query(
+			`INSERT INTO {prefix}edge_filters (role_id, parent_id, id, kind, sep, resource, action) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		), roleID, parentID, p.ID, p.Kind, p.Sep, p.Resource, p.Action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQLStorage) setDepth(ctx context.Context, tx *sql.Tx, roleID string, depth int) error {
+	_, err := tx.ExecContext(ctx, s.query(
+		`UPDATE {prefix}roles SET depth = ? WHERE id = ?`,
+	), depth, roleID)
+	return err
+}
+
+func (s *SQLStorage) insertSubject(ctx context.Context, tx *sql.Tx, subject, roleID string) error {
+	_, err := tx.ExecContext(ctx, s.query(
+		`INSERT INTO {prefix}subjects (subject, role_id) VALUES (?, ?)`,
+	), subject, roleID)
+	return err
+}
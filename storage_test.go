@@ -0,0 +1,120 @@
+package gorbac
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "modernc.org/sqlite"
+)
+
+func openSQLStorage(t *testing.T) *SQLStorage {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	s := NewSQLStorage(db)
+	version, err := s.Migrate(context.Background())
+	assert(t, err)
+	if version != len(sqlMigrations) {
+		t.Fatalf("Schema version %d expected, but %d got", len(sqlMigrations), version)
+	}
+	if version, err = s.Migrate(context.Background()); err != nil || version != len(sqlMigrations) {
+		t.Fatalf("Repeated migration should be a no-op: %d, %v", version, err)
+	}
+	return s
+}
+
+func TestSQLStorage_SaveLoad(t *testing.T) {
+	ctx := context.Background()
+	s := openSQLStorage(t)
+
+	rbac := prepareTree(t)
+	role, _, err := rbac.GetRole("reporter")
+	assert(t, err)
+	role.(*SimpleRole).
+		Assign(NewPermission("simple")).
+		Assign(&DeepPermission{IDStr: "doc/read", Sep: "/"}).
+		Assign(NewResourcePermission("invoice", "write")).
+		Assign(NewResourcePermission("invoice", "approve")).
+		Assign(MustTemplatePermission("user:{subject}:profile")).
+		Assign(Describe(NewDeepPermission("report"), Metadata{Title: "Reports", Labels: map[string]string{"group": "analytics"}}))
+	assert(t, rbac.AssignSubject("alice", "reporter"))
+	assert(t, rbac.SetMetadata("admin", Metadata{Title: "Administrator", Description: "Manages users", Labels: map[string]string{"team": "platform"}}))
+	assert(t, rbac.SetParent("observer", "moderator", InheritDepth(3)))
+	assert(t, rbac.SetInheritDepth("reporter", 1))
+
+	assert(t, SaveRBAC(ctx, s, rbac))
+	loaded, err := LoadRBAC(ctx, s)
+	assert(t, err)
+
+	expected, err := rbac.Export()
+	assert(t, err)
+	got, err := loaded.Export()
+	assert(t, err)
+	if !equalPolicies(expected, got) {
+		t.Fatalf("Policies differ:\n%+v\n%+v", expected, got)
+	}
+	if loaded.IsGranted("reporter", NewDeepPermission("doc:read"), nil) != rbac.IsGranted("reporter", NewDeepPermission("doc:read"), nil) {
+		t.Fatal("Loaded policy should grant the same")
+	}
+	if !loaded.IsGranted("observer", NewDeepPermission("system:shutdown"), nil) {
+		t.Fatal("Loaded hierarchy should be kept")
+	}
+}
+
+func TestSQLStorage_Apply(t *testing.T) {
+	ctx := context.Background()
+	s := openSQLStorage(t)
+
+	rbac := New()
+	var events []Event
+	rbac.OnChange(func(e Event) {
+		events = append(events, e)
+	})
+
+	assert(t, rbac.Add(NewRole("user").Assign(NewDeepPermission("profile"))))
+	assert(t, rbac.Add(NewRole("admin")))
+	assert(t, rbac.Add(NewRole("temp").SetMetadata(Metadata{Labels: map[string]string{"ttl": "1h"}})))
+	assert(t, rbac.SetParents("admin", []string{"user", "temp"}))
+	assert(t, rbac.Assign("admin", NewDeepPermission("user")))
+	assert(t, rbac.Assign("admin", Describe(NewPermission("stats"), Metadata{Labels: map[string]string{"group": "ops"}})))
+	assert(t, rbac.Revoke("admin", NewPermission("stats")))
+	assert(t, rbac.AssignSubject("alice", "admin"))
+	assert(t, rbac.AssignSubject("alice", "temp"))
+	assert(t, rbac.AssignSubject("bob", "user"))
+	assert(t, rbac.UnassignSubject("bob", "user"))
+	assert(t, rbac.Remove("temp"))
+	assert(t, rbac.SetMetadata("user", Metadata{Title: "User", Labels: map[string]string{"team": "core"}}))
+	assert(t, rbac.SetMetadata("user", Metadata{Title: "Member", Labels: map[string]string{"tier": "free"}}))
+	assert(t, rbac.SetParent("admin", "user", InheritDepth(1)))
+	assert(t, rbac.SetInheritDepth("admin", 3))
+
+	if len(events) != 17 {
+		t.Fatalf("17 events expected, but %d got", len(events))
+	}
+	assert(t, s.Apply(ctx, events))
+
+	policy, err := s.Load(ctx)
+	assert(t, err)
+	expected, err := rbac.Export()
+	assert(t, err)
+	if !equalPolicies(expected, policy) {
+		t.Fatalf("Policies differ:\n%+v\n%+v", expected, policy)
+	}
+
+	replica := New()
+	assert(t, replica.Apply(events...))
+	got, err := replica.Export()
+	assert(t, err)
+	if !equalPolicies(expected, got) {
+		t.Fatalf("Replayed policy differs:\n%+v\n%+v", expected, got)
+	}
+
+	if err := s.Apply(ctx, []Event{{Op: EventAddRole, Role: "user"}}); err == nil {
+		t.Fatal("Duplicated role should fail")
+	}
+}
@@ -0,0 +1,132 @@
+package gorbac
+
+import "sort"
+
+// AssignSubject assigns the role `id` to the `subject`, e.g. a user or a service account.
+// If the role is not existing, an error will be returned.
+func (rbac *RBAC) AssignSubject(subject string, id string) error {
+	rbac.mutex.Lock()
+	if _, ok := rbac.roles[id]; !ok {
+		rbac.mutex.Unlock()
+		return roleNotExist("AssignSubject", id)
+	}
+	rbac.assignSubject(subject, id)
+	rbac.emit(Event{Op: EventAssignSubject, Role: id, Subject: subject})
+	rbac.mutex.Unlock()
+	rbac.notify()
+	return nil
+}
+
+// UnassignSubject removes the role `id` from the `subject`.
+// If the role is not existing, an error will be returned.
+func (rbac *RBAC) UnassignSubject(subject string, id string) error {
+	rbac.mutex.Lock()
+	if _, ok := rbac.roles[id]; !ok {
+		rbac.mutex.Unlock()
+		return roleNotExist("UnassignSubject", id)
+	}
+	rbac.unassignSubject(subject, id)
+	rbac.emit(Event{Op: EventUnassignSubject, Role: id, Subject: subject})
+	rbac.mutex.Unlock()
+	rbac.notify()
+	return nil
+}
+
+// SubjectRoles returns roles assigned to the `subject`.
+func (rbac *RBAC) SubjectRoles(subject string) []string {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	var roles []string
+	for id := range rbac.subjects[subject] {
+		roles = append(roles, id)
+	}
+	return rbac.sortIDs(roles)
+}
+
+// RolesGranted returns roles having Permission `p` with the condition `assert`,
+// i.e. every role `id` for which IsGranted(id, p, assert) is true. The result is sorted.
+// Roles are found by walking children from the roles permitting `p` by their own permissions,
+// and checked against inheritance limits if there are any.
+func (rbac *RBAC) RolesGranted(p Permission, assert AssertionFunc) []string {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	return rbac.rolesGranted(p, assert)
+}
+
+func (rbac *RBAC) rolesGranted(p Permission, assert AssertionFunc) []string {
+	if p == nil {
+		return nil
+	}
+	granted := make(map[string]struct{})
+	for id, role := range rbac.roles {
+		if _, ok := granted[id]; ok || !role.Permit(p) {
+			continue
+		}
+		granted[id] = empty
+		for _, child := range rbac.reach(id, rbac.children) {
+			granted[child] = empty
+		}
+	}
+	if rbac.limited() {
+		for id := range granted {
+			if _, ok := rbac.recursionCheck(id, p); !ok {
+				delete(granted, id)
+			}
+		}
+	}
+
+	result := make([]string, 0, len(granted))
+	for id := range granted {
+		if assert == nil || assert(rbac, id, p) {
+			result = append(result, id)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// SubjectsGranted returns subjects having Permission `p` with the condition `assert`
+// through any of their roles. The result is sorted.
+func (rbac *RBAC) SubjectsGranted(p Permission, assert AssertionFunc) []string {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+
+	found := make(map[string]struct{})
+	for _, id := range rbac.rolesGranted(p, assert) {
+		for subject := range rbac.members[id] {
+			found[subject] = empty
+		}
+	}
+	var result []string
+	for subject := range found {
+		result = append(result, subject)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// assignSubject assigns the role `id` to the `subject` in both subject indexes.
+// The lock has to be held by the caller.
+func (rbac *RBAC) assignSubject(subject, id string) {
+	if _, ok := rbac.subjects[subject]; !ok {
+		rbac.subjects[subject] = make(map[string]struct{})
+	}
+	rbac.subjects[subject][id] = empty
+	if _, ok := rbac.members[id]; !ok {
+		rbac.members[id] = make(map[string]struct{})
+	}
+	rbac.members[id][subject] = empty
+}
+
+// unassignSubject removes the role `id` from the `subject` in both subject indexes.
+// The lock has to be held by the caller.
+func (rbac *RBAC) unassignSubject(subject, id string) {
+	delete(rbac.subjects[subject], id)
+	if len(rbac.subjects[subject]) == 0 {
+		delete(rbac.subjects, subject)
+	}
+	delete(rbac.members[id], subject)
+	if len(rbac.members[id]) == 0 {
+		delete(rbac.members, id)
+	}
+}
@@ -0,0 +1,115 @@
+package gorbac
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRBAC_Subjects(t *testing.T) {
+	rbac := prepareTree(t)
+
+	assert(t, rbac.AssignSubject("alice", "observer"))
+	assert(t, rbac.AssignSubject("alice", "reporter"))
+	assert(t, rbac.AssignSubject("bob", "root"))
+	if err := rbac.AssignSubject("carol", "not-exist"); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatalf("%s needed", ErrRoleNotExist)
+	}
+
+	if roles := rbac.SubjectRoles("alice"); !equalStrings(roles, []string{"observer", "reporter"}) {
+		t.Fatalf("Unexpected roles: %v", roles)
+	}
+
+	assert(t, rbac.UnassignSubject("alice", "observer"))
+	if roles := rbac.SubjectRoles("alice"); !equalStrings(roles, []string{"reporter"}) {
+		t.Fatalf("Unexpected roles: %v", roles)
+	}
+
+	assert(t, rbac.Remove("reporter"))
+	if roles := rbac.SubjectRoles("alice"); len(roles) != 0 {
+		t.Fatalf("Removed role should be unassigned: %v", roles)
+	}
+}
+
+func TestRBAC_RolesGranted(t *testing.T) {
+	rbac := prepareTree(t)
+
+	list := rbac.RolesGranted(NewDeepPermission("system"), nil)
+	if !equalStrings(list, []string{"admin", "moderator", "observer", "reporter", "root"}) {
+		t.Fatalf("Unexpected roles: %v", list)
+	}
+	list = rbac.RolesGranted(NewDeepPermission("task:create"), nil)
+	if !equalStrings(list, []string{"moderator", "observer", "reporter"}) {
+		t.Fatalf("Unexpected roles: %v", list)
+	}
+	list = rbac.RolesGranted(NewDeepPermission("task:delete"), func(_ *RBAC, id string, _ Permission) bool {
+		return id != "observer"
+	})
+	if !equalStrings(list, []string{"moderator", "reporter"}) {
+		t.Fatalf("Unexpected roles: %v", list)
+	}
+	for _, r := range rbac.GetRoles() {
+		granted := rbac.IsGranted(r.ID(), NewDeepPermission("user:read"), nil)
+		found := false
+		for _, id := range rbac.RolesGranted(NewDeepPermission("user:read"), nil) {
+			found = found || id == r.ID()
+		}
+		if granted != found {
+			t.Fatalf("RolesGranted differs from IsGranted on %s", r.ID())
+		}
+	}
+	if list := rbac.RolesGranted(nil, nil); len(list) != 0 {
+		t.Fatalf("Unexpected roles: %v", list)
+	}
+
+	assert(t, rbac.AssignSubject("alice", "observer"))
+	assert(t, rbac.AssignSubject("bob", "reporter"))
+	assert(t, rbac.AssignSubject("carol", "root"))
+	if list := rbac.SubjectsGranted(NewDeepPermission("user:read"), nil); !equalStrings(list, []string{"alice", "bob"}) {
+		t.Fatalf("Unexpected subjects: %v", list)
+	}
+	if list := rbac.SubjectsGranted(NewDeepPermission("system"), func(_ *RBAC, id string, _ Permission) bool {
+		return id == "reporter"
+	}); !equalStrings(list, []string{"bob"}) {
+		t.Fatalf("Unexpected subjects: %v", list)
+	}
+	if list := rbac.SubjectsGranted(NewPermission("nothing"), nil); len(list) != 0 {
+		t.Fatalf("Unexpected subjects: %v", list)
+	}
+}
+
+func TestRBAC_SubjectIndex(t *testing.T) {
+	rbac := prepareTree(t)
+
+	assert(t, rbac.AssignSubject("alice", "observer"))
+	assert(t, rbac.AssignSubject("alice", "reporter"))
+	assert(t, rbac.AssignSubject("bob", "reporter"))
+	assert(t, rbac.AssignSubject("carol", "root"))
+	assert(t, rbac.Rename("reporter", "writer"))
+	assert(t, rbac.Merge("observer", "writer"))
+	assert(t, rbac.UnassignSubject("bob", "writer"))
+	assert(t, rbac.Remove("root"))
+
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	count := 0
+	for subject, roles := range rbac.subjects {
+		for id := range roles {
+			if _, ok := rbac.members[id][subject]; !ok {
+				t.Fatalf("%s of %s is missing in the index", id, subject)
+			}
+			count++
+		}
+	}
+	for id, subjects := range rbac.members {
+		count -= len(subjects)
+		if len(subjects) == 0 {
+			t.Fatalf("Empty index entry of %s", id)
+		}
+	}
+	if count != 0 {
+		t.Fatalf("Index has stale entries: %v", rbac.members)
+	}
+	if list := rbac.subjectsOf("writer"); !equalStrings(list, []string{"alice"}) {
+		t.Fatalf("Unexpected subjects: %v", list)
+	}
+}
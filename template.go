@@ -0,0 +1,184 @@
+package gorbac
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidTemplate occurred if a permission template can't be parsed
+var ErrInvalidTemplate = errors.New("invalid permission template")
+
+// TemplatePermission is a layered permission with placeholders, e.g. `user:{subject}:profile:update`.
+// Placeholders are resolved from the variables bound to the checked permission
+// by Bind or IsGrantedWith, the resolved permission is matched as DeepPermission.
+// Literal braces are written as `{{` and `}}`.
+// A bound value containing the separator or a brace never matches,
+// so a variable can't widen the permission to other layers.
+type TemplatePermission struct {
+	IDStr string `json:"id"`
+	Sep   string `json:"sep"`
+	parts []templatePart
+}
+
+type templatePart struct {
+	text string
+	name string
+}
+
+// NewTemplatePermission parses the template `id` with the `:` separator.
+func NewTemplatePermission(id string) (*TemplatePermission, error) {
+	p := &TemplatePermission{IDStr: id, Sep: ":"}
+	if err := p.parse(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// MustTemplatePermission is like NewTemplatePermission but panics if the template can't be parsed.
+func MustTemplatePermission(id string) *TemplatePermission {
+	p, err := NewTemplatePermission(id)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+func (p *TemplatePermission) parse() error {
+	var parts []templatePart
+	var text strings.Builder
+	s := p.IDStr
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '{' && i+1 < len(s) && s[i+1] == '{':
+			text.WriteByte('{')
+			i++
+		case c == '}' && i+1 < len(s) && s[i+1] == '}':
+			text.WriteByte('}')
+			i++
+		case c == '{':
+			end := strings.IndexByte(s[i+1:], '}')
+			if end < 0 {
+				return fmt.Errorf("%w %q: unclosed placeholder", ErrInvalidTemplate, s)
+			}
+			name := s[i+1 : i+1+end]
+			if name == "" || strings.ContainsAny(name, "{"+p.Sep) {
+				return fmt.Errorf("%w %q: bad placeholder %q", ErrInvalidTemplate, s, name)
+			}
+			if text.Len() > 0 {
+				parts = append(parts, templatePart{text: text.String()})
+				text.Reset()
+			}
+			parts = append(parts, templatePart{name: name})
+			i += end + 1
+		case c == '}':
+			return fmt.Errorf("%w %q: unexpected `}`", ErrInvalidTemplate, s)
+		default:
+			text.WriteByte(c)
+		}
+	}
+	if text.Len() > 0 {
+		parts = append(parts, templatePart{text: text.String()})
+	}
+	p.parts = parts
+	return nil
+}
+
+// ID returns the unresolved template.
+func (p *TemplatePermission) ID() string {
+	return p.IDStr
+}
+
+// Placeholders returns names of the template placeholders.
+func (p *TemplatePermission) Placeholders() []string {
+	var names []string
+	for _, part := range p.templateParts() {
+		if part.name != "" {
+			names = append(names, part.name)
+		}
+	}
+	return names
+}
+
+// Resolve substitutes the placeholders by `vars`.
+// It returns false if a variable is missing or holds a forbidden value.
+func (p *TemplatePermission) Resolve(vars map[string]string) (*DeepPermission, bool) {
+	var b strings.Builder
+	for _, part := range p.templateParts() {
+		if part.name == "" {
+			b.WriteString(part.text)
+			continue
+		}
+		v, ok := vars[part.name]
+		if !ok || v == "" || strings.Contains(v, p.Sep) || strings.ContainsAny(v, "{}") {
+			return nil, false
+		}
+		b.WriteString(v)
+	}
+	return &DeepPermission{IDStr: b.String(), Sep: p.Sep}, true
+}
+
+// Match another permission.
+// The template matches itself by the ID, and a permission bound to variables
+// if the resolved template matches it.
+func (p *TemplatePermission) Match(a Permission) bool {
+	if p.IDStr == a.ID() {
+		return true
+	}
+	b, ok := a.(*BoundPermission)
+	if !ok {
+		return false
+	}
+	resolved, ok := p.Resolve(b.Vars)
+	if !ok {
+		return false
+	}
+	return resolved.Match(b.Permission)
+}
+
+func (p *TemplatePermission) templateParts() []templatePart {
+	if p.parts == nil && p.IDStr != "" {
+		// The template was built without a constructor, e.g. by json.Unmarshal.
+		cp := &TemplatePermission{IDStr: p.IDStr, Sep: p.Sep}
+		if cp.parse() != nil {
+			return nil
+		}
+		return cp.parts
+	}
+	return p.parts
+}
+
+// BoundPermission is a checked permission carrying variables for templates.
+// All other permissions see the wrapped permission.
+type BoundPermission struct {
+	Permission
+	Vars map[string]string
+}
+
+// Bind returns `p` bound to `vars`.
+func Bind(p Permission, vars map[string]string) *BoundPermission {
+	return &BoundPermission{Permission: p, Vars: vars}
+}
+
+// unbind returns the permission wrapped by BoundPermission or DescribedPermission.
+func unbind(p Permission) Permission {
+	for {
+		switch w := p.(type) {
+		case *BoundPermission:
+			p = w.Permission
+		case *DescribedPermission:
+			p = w.Permission
+		default:
+			return p
+		}
+	}
+}
+
+// IsGrantedWith tests if the role `id` has Permission `p` with the condition `assert`,
+// resolving permission templates by `vars`.
+func (rbac *RBAC) IsGrantedWith(id string, p Permission, vars map[string]string, assert AssertionFunc) bool {
+	if p == nil {
+		return false
+	}
+	return rbac.IsGranted(id, Bind(p, vars), assert)
+}
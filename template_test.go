@@ -0,0 +1,88 @@
+package gorbac
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestNewTemplatePermission(t *testing.T) {
+	p := MustTemplatePermission("user:{subject}:profile:{action}")
+	if names := p.Placeholders(); len(names) != 2 || names[0] != "subject" || names[1] != "action" {
+		t.Fatalf("Unexpected placeholders: %v", names)
+	}
+
+	for _, id := range []string{"user:{subject", "user:{}:read", "user:{a:b}", "user:}"} {
+		if _, err := NewTemplatePermission(id); !errors.Is(err, ErrInvalidTemplate) {
+			t.Errorf("%s: %s needed, but %v got", id, ErrInvalidTemplate, err)
+		}
+	}
+
+	escaped := MustTemplatePermission("doc:{{draft}}:{id}")
+	resolved, ok := escaped.Resolve(map[string]string{"id": "7"})
+	if !ok || resolved.ID() != "doc:{draft}:7" {
+		t.Fatalf("[doc:{draft}:7] expected, but %v got", resolved)
+	}
+}
+
+func TestTemplatePermission_Match(t *testing.T) {
+	p := MustTemplatePermission("user:{subject}:profile")
+	update := NewDeepPermission("user:42:profile:update")
+
+	if !p.Match(Bind(update, map[string]string{"subject": "42"})) {
+		t.Fatalf("%s should have the permission", p.ID())
+	}
+	if p.Match(Bind(update, map[string]string{"subject": "7"})) {
+		t.Fatalf("%s should not have the permission of another subject", p.ID())
+	}
+	if p.Match(update) {
+		t.Fatal("Unbound permission should not be resolved")
+	}
+	if p.Match(Bind(update, nil)) {
+		t.Fatal("Missing variable should not match")
+	}
+	if p.Match(Bind(NewDeepPermission("user:42:7:profile"), map[string]string{"subject": "42:7"})) {
+		t.Fatal("Variable with a separator should not match")
+	}
+	if !p.Match(NewPermission("user:{subject}:profile")) {
+		t.Fatal("Template should match itself")
+	}
+
+	text, err := json.Marshal(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cp TemplatePermission
+	assert(t, json.Unmarshal(text, &cp))
+	if !cp.Match(Bind(update, map[string]string{"subject": "42"})) {
+		t.Fatal("Unmarshaled template should match")
+	}
+}
+
+func TestRBAC_IsGrantedWith(t *testing.T) {
+	rbac := New()
+	user := NewRole("user").Assign(MustTemplatePermission("user:{subject}:profile:update"))
+	editor := NewRole("editor").Assign(NewDeepPermission("doc"))
+	assert(t, rbac.Add(user))
+	assert(t, rbac.Add(editor))
+	assert(t, rbac.SetParent("editor", "user"))
+
+	vars := map[string]string{"subject": "42"}
+	if !rbac.IsGrantedWith("editor", NewDeepPermission("user:42:profile:update"), vars, nil) {
+		t.Fatal("[editor] should update own profile")
+	}
+	if rbac.IsGrantedWith("editor", NewDeepPermission("user:7:profile:update"), vars, nil) {
+		t.Fatal("[editor] should not update another profile")
+	}
+	if !rbac.IsGrantedWith("editor", NewDeepPermission("doc:1:read"), vars, nil) {
+		t.Fatal("Bound permission should match a DeepPermission")
+	}
+	if rbac.IsGranted("editor", NewDeepPermission("user:42:profile:update"), nil) {
+		t.Fatal("Template should not be resolved without variables")
+	}
+
+	list := rbac.Permissions("editor")
+	if _, ok := list["user:{subject}:profile:update"]; !ok {
+		t.Fatalf("Permissions should show the unresolved template: %v", list)
+	}
+}
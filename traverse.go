@@ -0,0 +1,168 @@
+package gorbac
+
+import (
+	"context"
+	"errors"
+	"sort"
+)
+
+// SkipSubtree is returned by a TraverseHandler to skip roles reachable
+// only through the current one. It is not returned by the traversal.
+var SkipSubtree = errors.New("skip subtree")
+
+// Direction of a traversal from a role.
+type Direction int
+
+const (
+	// DirectionParents visits the role and its ancestors.
+	DirectionParents Direction = iota
+	// DirectionChildren visits the role and its descendants.
+	DirectionChildren
+)
+
+// TraverseHandler is a function defined by user to handle a role reached by a traversal,
+// `depth` is the number of edges from the starting role.
+type TraverseHandler func(r Role, depth int) error
+
+// WalkContext passes each Role to WalkHandler like Walk does,
+// but over a snapshot of roles and parents taken under the lock,
+// so the lock is not held while `h` is running.
+// It stops with the error of `ctx` once `ctx` is done.
+func WalkContext(ctx context.Context, rbac *RBAC, h WalkHandler) error {
+	if h == nil {
+		return nil
+	}
+	type entry struct {
+		role    Role
+		parents []string
+	}
+	rbac.mutex.RLock()
+	ids := make([]string, 0, len(rbac.roles))
+	for id := range rbac.roles {
+		ids = append(ids, id)
+	}
+	entries := make([]entry, 0, len(ids))
+	for _, id := range rbac.sortIDs(ids) {
+		var parents []string
+		for parent := range rbac.parents[id] {
+			parents = append(parents, parent)
+		}
+		entries = append(entries, entry{rbac.roles[id], rbac.sortIDs(parents)})
+	}
+	rbac.mutex.RUnlock()
+
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := h(e.role, e.parents); err != nil && err != SkipSubtree {
+			return err
+		}
+	}
+	return nil
+}
+
+// BFS visits the role `id` and roles reachable from it in `dir` breadth-first,
+// each role once, neighbours ordered by id.
+// The handler may return SkipSubtree to not follow edges of the current role.
+// The reachable roles are copied under the lock, which is not held while `h` is running.
+func (rbac *RBAC) BFS(ctx context.Context, id string, dir Direction, h TraverseHandler) error {
+	roles, edges, err := rbac.traversal("BFS", id, dir)
+	if err != nil || h == nil {
+		return err
+	}
+	type item struct {
+		id    string
+		depth int
+	}
+	visited := map[string]struct{}{id: empty}
+	queue := []item{{id, 0}}
+	for len(queue) > 0 {
+		it := queue[0]
+		queue = queue[1:]
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := h(roles[it.id], it.depth); err != nil {
+			if err == SkipSubtree {
+				continue
+			}
+			return err
+		}
+		for _, next := range edges[it.id] {
+			if _, ok := visited[next]; !ok {
+				visited[next] = empty
+				queue = append(queue, item{next, it.depth + 1})
+			}
+		}
+	}
+	return nil
+}
+
+// DFS visits the role `id` and roles reachable from it in `dir` depth-first in pre-order,
+// each role once, neighbours ordered by id.
+// The handler may return SkipSubtree to not follow edges of the current role.
+// The reachable roles are copied under the lock, which is not held while `h` is running.
+func (rbac *RBAC) DFS(ctx context.Context, id string, dir Direction, h TraverseHandler) error {
+	roles, edges, err := rbac.traversal("DFS", id, dir)
+	if err != nil || h == nil {
+		return err
+	}
+	visited := make(map[string]struct{})
+	var visit func(id string, depth int) error
+	visit = func(id string, depth int) error {
+		visited[id] = empty
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := h(roles[id], depth); err != nil {
+			if err == SkipSubtree {
+				return nil
+			}
+			return err
+		}
+		for _, next := range edges[id] {
+			if _, ok := visited[next]; ok {
+				continue
+			}
+			if err := visit(next, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return visit(id, 0)
+}
+
+// traversal copies roles reachable from `id` in `dir` with their sorted edges.
+func (rbac *RBAC) traversal(op, id string, dir Direction) (Roles, map[string][]string, error) {
+	rbac.mutex.RLock()
+	defer rbac.mutex.RUnlock()
+	if _, ok := rbac.roles[id]; !ok {
+		return nil, nil, roleNotExist(op, id)
+	}
+	index := rbac.parents
+	if dir == DirectionChildren {
+		index = rbac.children
+	}
+	roles := Roles{id: rbac.roles[id]}
+	edges := make(map[string][]string)
+	queue := []string{id}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for next := range index[current] {
+			role, ok := rbac.roles[next]
+			if !ok {
+				continue
+			}
+			edges[current] = append(edges[current], next)
+			if _, ok := roles[next]; !ok {
+				roles[next] = role
+				queue = append(queue, next)
+			}
+		}
+		sort.Strings(edges[current])
+	}
+	return roles, edges, nil
+}
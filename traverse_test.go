@@ -0,0 +1,101 @@
+package gorbac
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestWalkContext(t *testing.T) {
+	rbac := prepareTree(t)
+
+	// the lock is not held by the handler, so it may change RBAC
+	var ids []string
+	assert(t, WalkContext(context.Background(), rbac, func(r Role, _ []string) error {
+		ids = append(ids, r.ID())
+		return rbac.Remove(r.ID())
+	}))
+	if len(ids) != 5 || len(rbac.GetRoles()) != 0 {
+		t.Fatalf("Unexpected walk: %v", ids)
+	}
+
+	rbac = prepareTree(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	count := 0
+	err := WalkContext(ctx, rbac, func(Role, []string) error {
+		count++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || count != 1 {
+		t.Fatalf("Cancelled walk expected, but %v after %d roles got", err, count)
+	}
+}
+
+func TestRBAC_BFS(t *testing.T) {
+	rbac := prepareTree(t)
+	ctx := context.Background()
+
+	var ids []string
+	var depths []int
+	assert(t, rbac.BFS(ctx, "root", DirectionChildren, func(r Role, depth int) error {
+		ids = append(ids, r.ID())
+		depths = append(depths, depth)
+		return nil
+	}))
+	if !equalStrings(ids, []string{"root", "admin", "moderator", "observer", "reporter"}) || depths[4] != 3 {
+		t.Fatalf("Unexpected traversal: %v %v", ids, depths)
+	}
+
+	ids = nil
+	assert(t, rbac.BFS(ctx, "root", DirectionChildren, func(r Role, _ int) error {
+		ids = append(ids, r.ID())
+		if r.ID() == "moderator" {
+			return SkipSubtree
+		}
+		return nil
+	}))
+	if !equalStrings(ids, []string{"root", "admin", "moderator"}) {
+		t.Fatalf("Unexpected traversal: %v", ids)
+	}
+
+	if err := rbac.BFS(ctx, "not-exist", DirectionParents, nil); !errors.Is(err, ErrRoleNotExist) {
+		t.Fatalf("%s needed", ErrRoleNotExist)
+	}
+}
+
+func TestRBAC_DFS(t *testing.T) {
+	rbac := prepareTree(t)
+	assert(t, rbac.Add(NewRole("guest")))
+	assert(t, rbac.SetParent("observer", "guest"))
+	assert(t, rbac.SetParent("root", "observer"))
+	ctx := context.Background()
+
+	var ids []string
+	assert(t, rbac.DFS(ctx, "observer", DirectionParents, func(r Role, _ int) error {
+		ids = append(ids, r.ID())
+		return nil
+	}))
+	if !equalStrings(ids, []string{"observer", "guest", "moderator", "admin", "root"}) {
+		t.Fatalf("Unexpected traversal: %v", ids)
+	}
+
+	stop := errors.New("stop")
+	ids = nil
+	err := rbac.DFS(ctx, "observer", DirectionParents, func(r Role, _ int) error {
+		ids = append(ids, r.ID())
+		if r.ID() == "moderator" {
+			return stop
+		}
+		return nil
+	})
+	if err != stop || len(ids) != 3 {
+		t.Fatalf("Stopped traversal expected, but %v %v got", err, ids)
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := rbac.DFS(ctx, "observer", DirectionParents, func(Role, int) error { return nil }); !errors.Is(err, context.Canceled) {
+		t.Fatalf("%s needed, but %v got", context.Canceled, err)
+	}
+}